@@ -0,0 +1,302 @@
+package jchain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts for Time that read a number, or a string holding one, as the time
+// elapsed since the Unix epoch.
+const (
+	EpochSeconds = "epoch-seconds"
+	EpochMillis  = "epoch-millis"
+	EpochMicros  = "epoch-micros"
+	EpochNanos   = "epoch-nanos"
+)
+
+var epochUnits = map[string]float64{
+	EpochSeconds: 1e9,
+	EpochMillis:  1e6,
+	EpochMicros:  1e3,
+	EpochNanos:   1,
+}
+
+// Time parses a string with the first of layouts that matches, RFC 3339 by
+// default. Numbers are read as epoch times in the unit of the first epoch
+// layout given, or in seconds when no layouts are given at all. Epoch times
+// are returned in UTC.
+func (v *Value) Time(layouts ...string) (time.Time, error) {
+	if v.err != nil {
+		return time.Time{}, v.err
+	}
+
+	switch v.kind {
+	case String:
+		s, ok := v.data.(string)
+		if !ok {
+			return time.Time{}, fmt.Errorf("not string")
+		}
+		if len(layouts) == 0 {
+			layouts = []string{time.RFC3339Nano}
+		}
+		for _, layout := range layouts {
+			if unit, ok := epochUnits[layout]; ok {
+				if n, err := Parse(s).epochTime(unit); err == nil {
+					return n, nil
+				}
+				continue
+			}
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid time")
+	case Int, Float:
+		if len(layouts) == 0 {
+			return v.epochTime(epochUnits[EpochSeconds])
+		}
+		for _, layout := range layouts {
+			if unit, ok := epochUnits[layout]; ok {
+				return v.epochTime(unit)
+			}
+		}
+		return time.Time{}, fmt.Errorf("not string")
+	default:
+		return time.Time{}, fmt.Errorf("not time")
+	}
+}
+
+// epochTime converts a number counting units of unit nanoseconds.
+func (v *Value) epochTime(unit float64) (time.Time, error) {
+	if v.err != nil {
+		return time.Time{}, v.err
+	}
+
+	perSecond := int64(1e9 / unit)
+	switch val := v.data.(type) {
+	case int:
+		return time.Unix(int64(val)/perSecond, int64(val)%perSecond*int64(unit)).UTC(), nil
+	case int64:
+		return time.Unix(val/perSecond, val%perSecond*int64(unit)).UTC(), nil
+	case uint64:
+		if val > math.MaxInt64 {
+			return time.Time{}, fmt.Errorf("out of range")
+		}
+		n := int64(val)
+		return time.Unix(n/perSecond, n%perSecond*int64(unit)).UTC(), nil
+	case *big.Int:
+		return time.Time{}, fmt.Errorf("out of range")
+	case float64:
+		sec := val / float64(perSecond)
+		if math.IsNaN(sec) || sec > math.MaxInt64 || sec < math.MinInt64 {
+			return time.Time{}, fmt.Errorf("out of range")
+		}
+		whole, frac := math.Modf(sec)
+		return time.Unix(int64(whole), int64(math.Round(frac*1e9))).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("not number")
+	}
+}
+
+// Duration parses a Go duration string such as "1h30m" or an ISO 8601
+// duration such as "PT1H30M". Days count as 24 hours and weeks as 7 days;
+// years and months have no fixed length and are rejected. An Int is read as
+// nanoseconds, which is how encoding/json writes a time.Duration.
+func (v *Value) Duration() (time.Duration, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+
+	if v.kind == Int {
+		n, err := v.Int64()
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n), nil
+	}
+	if v.kind != String {
+		return 0, fmt.Errorf("not string")
+	}
+	s, ok := v.data.(string)
+	if !ok {
+		return 0, fmt.Errorf("not string")
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	return parseISODuration(s)
+}
+
+func parseISODuration(s string) (time.Duration, error) {
+	neg := false
+	rest := s
+	if strings.HasPrefix(rest, "-") {
+		neg, rest = true, rest[1:]
+	} else if strings.HasPrefix(rest, "+") {
+		rest = rest[1:]
+	}
+	if !strings.HasPrefix(rest, "P") || len(rest) == 1 {
+		return 0, fmt.Errorf("invalid duration")
+	}
+	rest = rest[1:]
+
+	var total float64
+	inTime := false
+	fields := 0
+	for rest != "" {
+		if rest[0] == 'T' {
+			if inTime || len(rest) == 1 {
+				return 0, fmt.Errorf("invalid duration")
+			}
+			inTime, rest = true, rest[1:]
+			continue
+		}
+		end := 0
+		for end < len(rest) && (('0' <= rest[end] && rest[end] <= '9') || rest[end] == '.' || rest[end] == ',') {
+			end++
+		}
+		if end == 0 || end == len(rest) {
+			return 0, fmt.Errorf("invalid duration")
+		}
+		n, err := strconv.ParseFloat(strings.Replace(rest[:end], ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration")
+		}
+
+		var unit time.Duration
+		switch designator := rest[end]; {
+		case !inTime && (designator == 'Y' || designator == 'M'):
+			return 0, fmt.Errorf("years and months have no fixed duration")
+		case !inTime && designator == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && designator == 'D':
+			unit = 24 * time.Hour
+		case inTime && designator == 'H':
+			unit = time.Hour
+		case inTime && designator == 'M':
+			unit = time.Minute
+		case inTime && designator == 'S':
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration")
+		}
+		total += n * float64(unit)
+		fields++
+		rest = rest[end+1:]
+	}
+	if fields == 0 {
+		return 0, fmt.Errorf("invalid duration")
+	}
+	// float64(math.MaxInt64) is 2^63, one past the largest Duration.
+	total = math.Round(total)
+	if total >= math.MaxInt64 {
+		return 0, fmt.Errorf("out of range")
+	}
+	if neg {
+		total = -total
+	}
+	return time.Duration(total), nil
+}
+
+// Bytes decodes a base64 string in the standard or URL-safe alphabet, with
+// or without padding.
+func (v *Value) Bytes() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	if v.kind != String {
+		return nil, fmt.Errorf("not string")
+	}
+	s, ok := v.data.(string)
+	if !ok {
+		return nil, fmt.Errorf("not string")
+	}
+
+	enc := base64.StdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.URLEncoding
+	}
+	if !strings.HasSuffix(s, "=") {
+		enc = enc.WithPadding(base64.NoPadding)
+	}
+	b, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64")
+	}
+	return b, nil
+}
+
+// UUID is a 16-byte universally unique identifier.
+type UUID [16]byte
+
+// String formats u in the canonical 8-4-4-4-12 form.
+func (u UUID) String() string {
+	buf := make([]byte, 0, 36)
+	for i, b := range u {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			buf = append(buf, '-')
+		}
+		buf = append(buf, hexDigits[b>>4], hexDigits[b&0x0f])
+	}
+	return string(buf)
+}
+
+// UUID parses a string in the canonical 8-4-4-4-12 form, in either case,
+// optionally wrapped in braces or prefixed with "urn:uuid:".
+func (v *Value) UUID() (UUID, error) {
+	if v.err != nil {
+		return UUID{}, v.err
+	}
+
+	if v.kind != String {
+		return UUID{}, fmt.Errorf("not string")
+	}
+	s, ok := v.data.(string)
+	if !ok {
+		return UUID{}, fmt.Errorf("not string")
+	}
+
+	s = strings.TrimPrefix(s, "urn:uuid:")
+	if len(s) == 38 && s[0] == '{' && s[37] == '}' {
+		s = s[1:37]
+	}
+	if len(s) != 36 {
+		return UUID{}, fmt.Errorf("invalid uuid")
+	}
+
+	var u UUID
+	j := 0
+	for i := 0; i < len(s); i++ {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			if s[i] != '-' {
+				return UUID{}, fmt.Errorf("invalid uuid")
+			}
+			continue
+		}
+		if !isHex(s[i]) || !isHex(s[i+1]) {
+			return UUID{}, fmt.Errorf("invalid uuid")
+		}
+		u[j] = unhex(s[i])<<4 | unhex(s[i+1])
+		j++
+		i++
+	}
+	return u, nil
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
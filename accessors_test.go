@@ -0,0 +1,135 @@
+package jchain
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	want := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	tests := []struct {
+		json    string
+		layouts []string
+		want    time.Time
+	}{
+		{`"2023-11-14T22:13:20Z"`, nil, want},
+		{`"2023-11-14T23:13:20.5+01:00"`, nil, want.Add(500 * time.Millisecond)},
+		{`"14/11/2023"`, []string{time.RFC3339, "02/01/2006"}, time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)},
+		{`1700000000`, nil, want},
+		{`1700000000.25`, nil, want.Add(250 * time.Millisecond)},
+		{`1700000000123`, []string{EpochMillis}, want.Add(123 * time.Millisecond)},
+		{`1700000000000001`, []string{time.RFC3339, EpochMicros}, want.Add(time.Microsecond)},
+		{`1700000000000000001`, []string{EpochNanos}, want.Add(1)},
+		{`"1700000000"`, []string{EpochSeconds}, want},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.json).Time(tt.layouts...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.json, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.json, got, tt.want)
+		}
+	}
+
+	for _, json := range []string{`"yesterday"`, `true`, `null`} {
+		if _, err := Parse(json).Time(); err == nil {
+			t.Errorf("%s: expected error", json)
+		}
+	}
+	if _, err := Parse(`12`).Time(time.RFC3339); err == nil || err.Error() != "not string" {
+		t.Errorf("expected not string, got %v", err)
+	}
+	if _, err := Parse(`{}`).Get("x").Time(); err == nil || err.Error() != "object doesnt have that key" {
+		t.Errorf("expected chain error, got %v", err)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		json string
+		want time.Duration
+	}{
+		{`"1h30m"`, 90 * time.Minute},
+		{`"-250ms"`, -250 * time.Millisecond},
+		{`"PT1H30M"`, 90 * time.Minute},
+		{`"P1DT2H"`, 26 * time.Hour},
+		{`"P2W"`, 14 * 24 * time.Hour},
+		{`"PT0.5S"`, 500 * time.Millisecond},
+		{`"-PT1M"`, -time.Minute},
+		{`1500000000`, 1500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.json).Duration()
+		if err != nil {
+			t.Errorf("%s: %v", tt.json, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.json, got, tt.want)
+		}
+	}
+
+	errs := map[string]string{
+		`"P1Y"`:  "years and months have no fixed duration",
+		`"P1M"`:  "years and months have no fixed duration",
+		`"P"`:    "invalid duration",
+		`"PT"`:   "invalid duration",
+		`"P1H"`:  "invalid duration",
+		`"soon"`: "invalid duration",
+		`1.5`:    "not string",
+		// 2^63 nanoseconds, one past the largest Duration.
+		`"PT2562047H47M16.854775808S"`:  "out of range",
+		`"PT9223372036.854775808S"`:     "out of range",
+		`"-PT2562047H47M16.854775808S"`: "out of range",
+	}
+	for json, msg := range errs {
+		if _, err := Parse(json).Duration(); err == nil || err.Error() != msg {
+			t.Errorf("%s: expected %q, got %v", json, msg, err)
+		}
+	}
+	if d, err := Parse(`"PT2562047H"`).Duration(); err != nil || d != 2562047*time.Hour {
+		t.Errorf("expected 2562047h, got %v, %v", d, err)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	want := []byte{0xfb, 0xff, 0xbf, 'h', 'i'}
+	for _, json := range []string{`"+/+/aGk="`, `"+/+/aGk"`, `"-_-_aGk="`, `"-_-_aGk"`} {
+		got, err := Parse(json).Bytes()
+		if err != nil {
+			t.Errorf("%s: %v", json, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: got %x, want %x", json, got, want)
+		}
+	}
+	if _, err := Parse(`"not base64!"`).Bytes(); err == nil || err.Error() != "invalid base64" {
+		t.Errorf("expected invalid base64, got %v", err)
+	}
+	if _, err := Parse(`5`).Bytes(); err == nil || err.Error() != "not string" {
+		t.Errorf("expected not string, got %v", err)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	const want = "123e4567-e89b-12d3-a456-426614174000"
+	for _, json := range []string{`"123e4567-e89b-12d3-a456-426614174000"`, `"123E4567-E89B-12D3-A456-426614174000"`, `"{123e4567-e89b-12d3-a456-426614174000}"`, `"urn:uuid:123e4567-e89b-12d3-a456-426614174000"`} {
+		u, err := Parse(json).UUID()
+		if err != nil {
+			t.Errorf("%s: %v", json, err)
+			continue
+		}
+		if u.String() != want {
+			t.Errorf("%s: got %s", json, u)
+		}
+	}
+	for _, json := range []string{`"123e4567e89b12d3a456426614174000"`, `"123e4567-e89b-12d3-a456-42661417400g"`, `"123e4567-e89b-12d3-a456_426614174000"`} {
+		if _, err := Parse(json).UUID(); err == nil || err.Error() != "invalid uuid" {
+			t.Errorf("%s: expected invalid uuid, got %v", json, err)
+		}
+	}
+}
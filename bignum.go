@@ -0,0 +1,61 @@
+package jchain
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// BigInt returns an Int as a new *big.Int. Integers outside both the int64
+// and the uint64 range, which ParseOptions.BigNumbers, From and the CBOR
+// decoder keep exact, can only be read in full this way.
+func (v *Value) BigInt() (*big.Int, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	switch val := v.data.(type) {
+	case int64:
+		return big.NewInt(val), nil
+	case uint64:
+		return new(big.Int).SetUint64(val), nil
+	case *big.Int:
+		return new(big.Int).Set(val), nil
+	default:
+		return nil, fmt.Errorf("not int")
+	}
+}
+
+// normalizeBig stores n the way the parser stores integers: as int64 or
+// uint64 when one of them holds it, and as a copy of n otherwise.
+func normalizeBig(n *big.Int) any {
+	switch {
+	case n.IsInt64():
+		return n.Int64()
+	case n.IsUint64():
+		return n.Uint64()
+	default:
+		return new(big.Int).Set(n)
+	}
+}
+
+// toBig widens any integer to a *big.Int. It reports false for other data.
+func toBig(val any) (*big.Int, bool) {
+	switch val := val.(type) {
+	case int:
+		return big.NewInt(int64(val)), true
+	case int64:
+		return big.NewInt(val), true
+	case uint64:
+		return new(big.Int).SetUint64(val), true
+	case *big.Int:
+		return val, true
+	}
+	return nil, false
+}
+
+// bigFloat converts n to the nearest float64 and reports whether that is
+// exact.
+func bigFloat(n *big.Int) (float64, bool) {
+	f, acc := new(big.Float).SetInt(n).Float64()
+	return f, acc == big.Exact
+}
@@ -0,0 +1,85 @@
+package jchain
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestBigNumbers(t *testing.T) {
+	const huge = "123456789012345678901234567890"
+	v := ParseWithOptions(`[`+huge+`, -`+huge+`, 18446744073709551615, 1]`, ParseOptions{BigNumbers: true})
+	if err := v.Error(); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{huge, "-" + huge, "18446744073709551615", "1"} {
+		elem := v.Index(i)
+		if elem.Kind() != Int {
+			t.Errorf("%d: expected an Int, got %v", i, elem.Kind())
+		}
+		n, err := elem.BigInt()
+		if err != nil || n.String() != want {
+			t.Errorf("%d: got %v, %v, want %s", i, n, err, want)
+		}
+	}
+	if _, err := v.Index(0).Int64(); err == nil || err.Error() != "out of range" {
+		t.Errorf("expected Int64 to be out of range, got %v", err)
+	}
+	if text, _ := v.MarshalJSON(); string(text) != `[`+huge+`,-`+huge+`,18446744073709551615,1]` {
+		t.Errorf("got %s", text)
+	}
+
+	if k := Parse(huge).Kind(); k != Float {
+		t.Errorf("expected a float without BigNumbers, got %v", k)
+	}
+	if _, err := Parse(`"x"`).BigInt(); err == nil {
+		t.Error("expected an error for a string")
+	}
+}
+
+func TestBigNumbersFrom(t *testing.T) {
+	n, _ := new(big.Int).SetString("-98765432109876543210", 10)
+	v := From(map[string]any{"n": n, "small": big.NewInt(7)})
+	if !v.Equal(ParseWithOptions(`{"n": -98765432109876543210, "small": 7}`, ParseOptions{BigNumbers: true})) {
+		t.Errorf("got %v, %v", v, v.Error())
+	}
+	if _, ok := v.Get("small").data.(int64); !ok {
+		t.Errorf("expected a small *big.Int to be stored as int64, got %T", v.Get("small").data)
+	}
+	got, _ := v.Get("n").BigInt()
+	if got.Cmp(n) != 0 || got == n {
+		t.Errorf("expected a copy of %v, got %v", n, got)
+	}
+}
+
+func TestBigNumbersEqual(t *testing.T) {
+	opts := ParseOptions{BigNumbers: true}
+	a := ParseWithOptions(`36893488147419103232`, opts) // 2^65
+	b := ParseWithOptions(`36893488147419103232`, opts)
+	float := Parse(`36893488147419103232.0`)
+	if !a.Equal(b) {
+		t.Error("expected equal big integers to be equal")
+	}
+	if a.Equal(float) {
+		t.Error("expected a big Int and a Float to differ with exact numbers")
+	}
+	if !a.EqualWith(float, NumericNumbers) || !float.EqualWith(a, NumericNumbers) {
+		t.Error("expected 2^65 and 2^65 as a float to be equal with numeric numbers")
+	}
+	odd := ParseWithOptions(`36893488147419103233`, opts)
+	if odd.EqualWith(float, NumericNumbers) {
+		t.Error("expected 2^65+1 to differ from the float that rounds to it")
+	}
+
+	for _, mode := range []NumberMode{ExactNumbers, NumericNumbers} {
+		ha, _ := a.HashWith(mode)
+		hb, _ := b.HashWith(mode)
+		if ha != hb {
+			t.Errorf("mode %d: equal big integers hash differently", mode)
+		}
+	}
+	ha, _ := a.HashWith(NumericNumbers)
+	hf, _ := float.HashWith(NumericNumbers)
+	if ha != hf {
+		t.Error("expected 2^65 to hash like 2^65 as a float with numeric numbers")
+	}
+}
@@ -0,0 +1,91 @@
+package jchain
+
+import (
+	"fmt"
+)
+
+// Builder assembles an object or array step by step:
+//
+//	v := NewObject().
+//		Set("name", "Alice").
+//		SetArray("tags", "a", "b").
+//		Set("address", NewObject().Set("city", "Paris")).
+//		Value()
+//
+// Values are converted with From, so they may be Go data, *Value or another
+// *Builder. The first error is kept and returned by Value.
+type Builder struct {
+	data any
+	err  error
+}
+
+func NewObject() *Builder {
+	return &Builder{data: map[string]any{}}
+}
+
+func NewArray(items ...any) *Builder {
+	return (&Builder{data: []any{}}).Append(items...)
+}
+
+// Set sets the member key of an object.
+func (b *Builder) Set(key string, val any) *Builder {
+	if b.err != nil {
+		return b
+	}
+	obj, ok := b.data.(map[string]any)
+	if !ok {
+		b.err = fmt.Errorf("not object")
+		return b
+	}
+	v := From(val)
+	if v.err != nil {
+		b.err = v.err
+		return b
+	}
+	obj[key] = v.data
+	return b
+}
+
+// SetArray sets the member key of an object to an array of items.
+func (b *Builder) SetArray(key string, items ...any) *Builder {
+	return b.Set(key, NewArray(items...))
+}
+
+// Append adds items to the end of an array.
+func (b *Builder) Append(items ...any) *Builder {
+	if b.err != nil {
+		return b
+	}
+	arr, ok := b.data.([]any)
+	if !ok {
+		b.err = fmt.Errorf("not array")
+		return b
+	}
+	for _, item := range items {
+		v := From(item)
+		if v.err != nil {
+			b.err = v.err
+			return b
+		}
+		arr = append(arr, v.data)
+	}
+	b.data = arr
+	return b
+}
+
+// Value returns the built value. Later changes to the builder do not affect
+// it.
+func (b *Builder) Value() *Value {
+	data, err := b.build()
+	if err != nil {
+		return &Value{err: err}
+	}
+	return &Value{kind: getKind(data), data: data}
+}
+
+func (b *Builder) build() (any, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return cloneData(b.data), nil
+}
@@ -0,0 +1,189 @@
+package jchain
+
+import (
+	"fmt"
+	"hash"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Canonical encodes the value following the JSON Canonicalization Scheme of
+// RFC 8785: no whitespace, object keys sorted by their UTF-16 code units,
+// numbers serialized as ECMAScript does and minimal string escaping. Integers
+// are converted to IEEE 754 doubles first, as I-JSON requires.
+func (v *Value) Canonical() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	return appendCanonical(nil, v.data)
+}
+
+// CanonicalHash parses json, canonicalizes it and returns its digest
+// computed with h.
+func CanonicalHash(json string, h hash.Hash) ([]byte, error) {
+	b, err := Parse(json).Canonical()
+	if err != nil {
+		return nil, err
+	}
+	h.Reset()
+	h.Write(b)
+	return h.Sum(nil), nil
+}
+
+func appendCanonical(buf []byte, val any) ([]byte, error) {
+	var err error
+	switch val := val.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return lessUTF16(keys[i], keys[j])
+		})
+		buf = append(buf, '{')
+		for i, key := range keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendCanonicalString(buf, key)
+			buf = append(buf, ':')
+			if buf, err = appendCanonical(buf, val[key]); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+	case []any:
+		buf = append(buf, '[')
+		for i, elem := range val {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			if buf, err = appendCanonical(buf, elem); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+	case string:
+		return appendCanonicalString(buf, val), nil
+	case int64:
+		return appendES6Number(buf, float64(val))
+	case uint64:
+		return appendES6Number(buf, float64(val))
+	case *big.Int:
+		f, _ := bigFloat(val)
+		return appendES6Number(buf, f)
+	case float64:
+		return appendES6Number(buf, val)
+	case bool:
+		return strconv.AppendBool(buf, val), nil
+	case nil:
+		return append(buf, "null"...), nil
+	default:
+		return nil, fmt.Errorf("invalid value %T", val)
+	}
+}
+
+func lessUTF16(a, b string) bool {
+	ua := utf16.Encode([]rune(a))
+	ub := utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+func appendCanonicalString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			buf = utf8.AppendRune(buf, r)
+			i += size
+			continue
+		}
+		switch c {
+		case '"', '\\':
+			buf = append(buf, '\\', c)
+		case '\b':
+			buf = append(buf, '\\', 'b')
+		case '\f':
+			buf = append(buf, '\\', 'f')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if c < 0x20 {
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			} else {
+				buf = append(buf, c)
+			}
+		}
+		i++
+	}
+	return append(buf, '"')
+}
+
+// appendES6Number formats f like ECMAScript's Number.prototype.toString,
+// using the shortest digit string that round-trips.
+func appendES6Number(buf []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("unsupported float %v", f)
+	}
+	if f == 0 {
+		return append(buf, '0'), nil
+	}
+	if f < 0 {
+		buf = append(buf, '-')
+		f = -f
+	}
+
+	// Shortest digits as d.ddde±x, where n below is the decimal point
+	// position relative to the start of the digits.
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	k, n := len(digits), x+1
+
+	switch {
+	case k <= n && n <= 21:
+		buf = append(buf, digits...)
+		for i := 0; i < n-k; i++ {
+			buf = append(buf, '0')
+		}
+	case 0 < n && n <= 21:
+		buf = append(buf, digits[:n]...)
+		buf = append(buf, '.')
+		buf = append(buf, digits[n:]...)
+	case -6 < n && n <= 0:
+		buf = append(buf, '0', '.')
+		for i := 0; i < -n; i++ {
+			buf = append(buf, '0')
+		}
+		buf = append(buf, digits...)
+	default:
+		buf = append(buf, digits[0])
+		if k > 1 {
+			buf = append(buf, '.')
+			buf = append(buf, digits[1:]...)
+		}
+		buf = append(buf, 'e')
+		if n-1 >= 0 {
+			buf = append(buf, '+')
+		}
+		buf = strconv.AppendInt(buf, int64(n-1), 10)
+	}
+	return buf, nil
+}
@@ -0,0 +1,107 @@
+package jchain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"testing"
+)
+
+// Number serialization samples from RFC 8785, Appendix B.
+var es6NumberTests = []struct {
+	bits uint64
+	want string
+}{
+	{0x0000000000000000, "0"},
+	{0x8000000000000000, "0"},
+	{0x0000000000000001, "5e-324"},
+	{0x8000000000000001, "-5e-324"},
+	{0x7fefffffffffffff, "1.7976931348623157e+308"},
+	{0xffefffffffffffff, "-1.7976931348623157e+308"},
+	{0x4340000000000000, "9007199254740992"},
+	{0xc340000000000000, "-9007199254740992"},
+	{0x4430000000000000, "295147905179352830000"},
+	{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+	{0x44b52d02c7e14af6, "1e+23"},
+	{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+	{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+	{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+	{0x444b1ae4d6e2ef50, "1e+21"},
+	{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+	{0x3eb0c6f7a0b5ed8d, "0.000001"},
+	{0x41b3de4355555553, "333333333.3333332"},
+	{0x41b3de4355555554, "333333333.33333325"},
+	{0x41b3de4355555555, "333333333.3333333"},
+	{0x41b3de4355555556, "333333333.3333334"},
+	{0x41b3de4355555557, "333333333.33333343"},
+	{0xbecbf647612f3696, "-0.0000033333333333333333"},
+	{0x43143ff3c1cb0959, "1424953923781206.2"},
+}
+
+func TestES6Number(t *testing.T) {
+	for _, tt := range es6NumberTests {
+		got, err := appendES6Number(nil, math.Float64frombits(tt.bits))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%#016x: got %s, want %s", tt.bits, got, tt.want)
+		}
+	}
+	for _, bits := range []uint64{0x7fffffffffffffff, 0x7ff0000000000000} {
+		if _, err := appendES6Number(nil, math.Float64frombits(bits)); err == nil {
+			t.Errorf("%#016x: expected error", bits)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		// RFC 8785, Section 3.2.2.
+		{`{
+			"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			"literals": [null, true, false]
+		}`, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		// RFC 8785, Section 3.2.3.
+		{`{
+			"\u20ac": "Euro Sign",
+			"\r": "Carriage Return",
+			"\ufb33": "Hebrew Letter Dalet With Dagesh",
+			"1": "One",
+			"\ud83d\ude00": "Emoji: Grinning Face",
+			"\u0080": "Control",
+			"\u00f6": "Latin Small Letter O With Diaeresis"
+		}`, "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"},
+		{`[10, -0.0, 1e21, 9007199254740993]`, `[10,0,1e+21,9007199254740992]`},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input).Canonical()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("got %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalHash(t *testing.T) {
+	a, err := CanonicalHash(`{"b": 2, "a": [1.0, "x"]}`, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CanonicalHash(`{"a":[1,"x"],"b":2.00}`, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sha256.Sum256([]byte(`{"a":[1,"x"],"b":2}`))
+	if hex.EncodeToString(a) != hex.EncodeToString(want[:]) || hex.EncodeToString(b) != hex.EncodeToString(want[:]) {
+		t.Errorf("unexpected digests %x and %x", a, b)
+	}
+	if _, err := CanonicalHash(`{"a":`, sha256.New()); err == nil {
+		t.Error("expected parse error")
+	}
+}
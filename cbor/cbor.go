@@ -0,0 +1,598 @@
+// Package cbor converts between CBOR (RFC 8949) and jchain values, so that
+// CBOR payloads can be traversed with the same API as JSON.
+//
+// Decoding follows the number handling of the JSON parser: integers,
+// including bignums (tags 2 and 3), become int64, or uint64 above
+// math.MaxInt64. Integers beyond both stay exact as with
+// jchain.ParseOptions.BigNumbers and are read back with Value.BigInt. Byte
+// strings become standard base64 strings, and date/time (tag 0) and epoch
+// time (tag 1) become RFC 3339 strings. Definite and indefinite lengths are
+// both accepted.
+//
+// Encode writes the core deterministic encoding of RFC 8949 section 4.2,
+// with integers beyond 64 bits as bignums.
+package cbor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mntwlds/jchain"
+	"github.com/mntwlds/jchain/internal/core"
+)
+
+const (
+	majorUint = iota
+	majorNegInt
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+const (
+	tagDateTime  = 0
+	tagEpoch     = 1
+	tagPosBignum = 2
+	tagNegBignum = 3
+)
+
+// indefinite is the additional information marking an indefinite length.
+const indefinite = 31
+
+type DecodeOptions struct {
+	// MaxDepth limits the nesting of maps, arrays and tags. Zero means no
+	// limit, as in jchain.ParseOptions.
+	MaxDepth int
+	// Tag converts tags other than 0 to 3. Its result is converted with
+	// jchain.From. Without it the tag is dropped and its content kept.
+	Tag func(number uint64, content *jchain.Value) (any, error)
+}
+
+// Decode reads a single CBOR data item, nested at most as deep as
+// jchain.Parse allows.
+func Decode(data []byte) *jchain.Value {
+	return DecodeWithOptions(data, DecodeOptions{MaxDepth: 1000})
+}
+
+func DecodeWithOptions(data []byte, opts DecodeOptions) *jchain.Value {
+	d := &decoder{data: data, opts: opts}
+	val, err := d.value()
+	err = d.unexpectedBreak(err)
+	if err == nil && d.off < len(d.data) {
+		err = fmt.Errorf("unexpected data after value at offset %d", d.off)
+	}
+	if err != nil {
+		return core.Error(err).(*jchain.Value)
+	}
+	return core.Value(val).(*jchain.Value)
+}
+
+type decoder struct {
+	data  []byte
+	off   int
+	depth int
+	opts  DecodeOptions
+}
+
+// errBreak is returned by value when it reads the break code that ends an
+// indefinite-length item.
+var errBreak = fmt.Errorf("break")
+
+func (d *decoder) read(n uint64) ([]byte, error) {
+	if uint64(len(d.data)-d.off) < n {
+		return nil, fmt.Errorf("unexpected end of input at offset %d", len(d.data))
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// head reads the initial byte of an item and its argument.
+func (d *decoder) head() (major byte, info byte, arg uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		b, err := d.read(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		switch len(b) {
+		case 1:
+			arg = uint64(b[0])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(b))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(b))
+		default:
+			arg = binary.BigEndian.Uint64(b)
+		}
+		return major, info, arg, nil
+	case info == indefinite && major != majorUint && major != majorNegInt && major != majorTag:
+		return major, info, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid additional information %d at offset %d", info, d.off-1)
+	}
+}
+
+func (d *decoder) enter(start int) error {
+	if d.opts.MaxDepth > 0 && d.depth >= d.opts.MaxDepth {
+		return fmt.Errorf("maximum depth exceeded at offset %d", start)
+	}
+	d.depth++
+	return nil
+}
+
+// value reads a data item as jchain data.
+func (d *decoder) value() (any, error) {
+	val, err := d.item()
+	return byteText(val), err
+}
+
+// byteText turns a byte string into the base64 text it decodes to.
+func byteText(val any) any {
+	if b, ok := val.([]byte); ok {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return val
+}
+
+// item reads a data item like value, but leaves byte strings as []byte for
+// the tags and map keys that look at them.
+func (d *decoder) item() (any, error) {
+	start := d.off
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUint:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case majorNegInt:
+		if arg <= math.MaxInt64 {
+			return -1 - int64(arg), nil
+		}
+		n := new(big.Int).SetUint64(arg)
+		return bignum(n.Not(n)), nil
+	case majorBytes, majorText:
+		b, err := d.stringData(major, info, arg, start)
+		if err != nil {
+			return nil, err
+		}
+		if major == majorBytes {
+			return b, nil
+		}
+		if !utf8.Valid(b) {
+			return nil, fmt.Errorf("invalid UTF-8 in string at offset %d", start)
+		}
+		return string(b), nil
+	case majorArray:
+		return d.array(info, arg, start)
+	case majorMap:
+		return d.mapItem(info, arg, start)
+	case majorTag:
+		return d.tag(arg, start)
+	default:
+		return d.simple(info, arg, start)
+	}
+}
+
+// stringData returns the bytes of a byte or text string, joining the chunks
+// of an indefinite-length one.
+func (d *decoder) stringData(major, info byte, arg uint64, start int) ([]byte, error) {
+	if info != indefinite {
+		b, err := d.read(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	}
+	var buf []byte
+	for {
+		chunkStart := d.off
+		if d.off < len(d.data) && d.data[d.off] == 0xff {
+			d.off++
+			return buf, nil
+		}
+		m, i, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || i == indefinite {
+			return nil, fmt.Errorf("invalid chunk in indefinite-length string at offset %d", chunkStart)
+		}
+		b, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		if major == majorText && !utf8.Valid(b) {
+			return nil, fmt.Errorf("invalid UTF-8 in string at offset %d", chunkStart)
+		}
+		buf = append(buf, b...)
+	}
+}
+
+func (d *decoder) array(info byte, n uint64, start int) (any, error) {
+	if err := d.enter(start); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	if info == indefinite {
+		arr := []any{}
+		for {
+			val, err := d.value()
+			if err == errBreak {
+				return arr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+	}
+	// Every element takes at least one byte, which bounds the allocation.
+	if n > uint64(len(d.data)-d.off) {
+		return nil, fmt.Errorf("unexpected end of input at offset %d", len(d.data))
+	}
+	arr := make([]any, n)
+	for i := range arr {
+		val, err := d.value()
+		if err != nil {
+			return nil, d.unexpectedBreak(err)
+		}
+		arr[i] = val
+	}
+	return arr, nil
+}
+
+func (d *decoder) mapItem(info byte, n uint64, start int) (any, error) {
+	if err := d.enter(start); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	if info != indefinite && n > uint64(len(d.data)-d.off)/2 {
+		return nil, fmt.Errorf("unexpected end of input at offset %d", len(d.data))
+	}
+	obj := map[string]any{}
+	for i := uint64(0); info == indefinite || i < n; i++ {
+		keyStart := d.off
+		k, err := d.item()
+		if err == errBreak && info == indefinite {
+			return obj, nil
+		}
+		if err != nil {
+			return nil, d.unexpectedBreak(err)
+		}
+		var key string
+		switch k := k.(type) {
+		case string:
+			key = k
+		case int64:
+			key = strconv.FormatInt(k, 10)
+		case uint64:
+			key = strconv.FormatUint(k, 10)
+		case *big.Int:
+			key = k.String()
+		default:
+			return nil, fmt.Errorf("unsupported map key type %T at offset %d", k, keyStart)
+		}
+		if _, ok := obj[key]; ok {
+			return nil, fmt.Errorf("duplicate key %s at offset %d", key, keyStart)
+		}
+		val, err := d.value()
+		if err != nil {
+			return nil, d.unexpectedBreak(err)
+		}
+		obj[key] = val
+	}
+	return obj, nil
+}
+
+func (d *decoder) unexpectedBreak(err error) error {
+	if err == errBreak {
+		return fmt.Errorf("unexpected break at offset %d", d.off-1)
+	}
+	return err
+}
+
+func (d *decoder) tag(number uint64, start int) (any, error) {
+	if err := d.enter(start); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	content, err := d.item()
+	if err != nil {
+		return nil, d.unexpectedBreak(err)
+	}
+
+	switch number {
+	case tagDateTime:
+		s, ok := content.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid content for tag 0 at offset %d", start)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			return nil, fmt.Errorf("invalid date/time %q at offset %d", s, start)
+		}
+		return s, nil
+	case tagEpoch:
+		var t time.Time
+		switch n := content.(type) {
+		case int64:
+			t = time.Unix(n, 0)
+		case uint64, *big.Int:
+			return nil, fmt.Errorf("epoch time out of range at offset %d", start)
+		case float64:
+			if math.Abs(n) > 1<<62 {
+				return nil, fmt.Errorf("epoch time out of range at offset %d", start)
+			}
+			sec, frac := math.Modf(n)
+			t = time.Unix(int64(sec), int64(math.Round(frac*1e9)))
+		default:
+			return nil, fmt.Errorf("invalid content for tag 1 at offset %d", start)
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	case tagPosBignum, tagNegBignum:
+		b, ok := content.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("invalid content for tag %d at offset %d", number, start)
+		}
+		n := new(big.Int).SetBytes(b)
+		if number == tagNegBignum {
+			n.Not(n)
+		}
+		return bignum(n), nil
+	}
+
+	content = byteText(content)
+	if d.opts.Tag == nil {
+		return content, nil
+	}
+	val, err := d.opts.Tag(number, core.Value(content).(*jchain.Value))
+	if err != nil {
+		return nil, err
+	}
+	return jchain.From(val).Any()
+}
+
+// bignum narrows n to int64 or uint64 when it fits, as the JSON parser
+// does. Larger values stay *big.Int so that they keep their exact value.
+func bignum(n *big.Int) any {
+	switch {
+	case n.IsInt64():
+		return n.Int64()
+	case n.IsUint64():
+		return n.Uint64()
+	default:
+		return n
+	}
+}
+
+func (d *decoder) simple(info byte, arg uint64, start int) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		// undefined has no JSON counterpart and reads as null.
+		return nil, nil
+	case 25:
+		return checkFloat(float16(uint16(arg)))
+	case 26:
+		return checkFloat(float64(math.Float32frombits(uint32(arg))))
+	case 27:
+		return checkFloat(math.Float64frombits(arg))
+	case indefinite:
+		return nil, errBreak
+	default:
+		return nil, fmt.Errorf("unsupported simple value %d at offset %d", arg, start)
+	}
+}
+
+// checkFloat rejects the NaN and infinities that JSON cannot hold.
+func checkFloat(f float64) (any, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("unsupported float %v", f)
+	}
+	return f, nil
+}
+
+func float16(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// Encode writes v in the core deterministic encoding: the shortest form of
+// every integer, length and float that preserves its value, definite lengths
+// only, and map keys sorted by their encoded bytes.
+func Encode(v *jchain.Value) ([]byte, error) {
+	data, err := v.Any()
+	if err != nil {
+		return nil, err
+	}
+	return appendValue(nil, data)
+}
+
+func appendHead(buf []byte, major byte, arg uint64) []byte {
+	m := major << 5
+	switch {
+	case arg < 24:
+		return append(buf, m|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(buf, m|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return append(buf, m|25, byte(arg>>8), byte(arg))
+	case arg <= math.MaxUint32:
+		return append(buf, m|26, byte(arg>>24), byte(arg>>16), byte(arg>>8), byte(arg))
+	default:
+		buf = append(buf, m|27)
+		for shift := 56; shift >= 0; shift -= 8 {
+			buf = append(buf, byte(arg>>shift))
+		}
+		return buf
+	}
+}
+
+func appendValue(buf []byte, val any) ([]byte, error) {
+	switch val := val.(type) {
+	case nil:
+		return append(buf, 0xf6), nil
+	case bool:
+		if val {
+			return append(buf, 0xf5), nil
+		}
+		return append(buf, 0xf4), nil
+	case int:
+		return appendInt(buf, int64(val)), nil
+	case int64:
+		return appendInt(buf, val), nil
+	case uint64:
+		return appendHead(buf, majorUint, val), nil
+	case *big.Int:
+		return appendBignum(buf, val), nil
+	case float64:
+		return appendFloat(buf, val), nil
+	case string:
+		return append(appendHead(buf, majorText, uint64(len(val))), val...), nil
+	case []any:
+		buf = appendHead(buf, majorArray, uint64(len(val)))
+		for _, elem := range val {
+			var err error
+			if buf, err = appendValue(buf, elem); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]any:
+		keys := make([][]byte, 0, len(val))
+		byKey := make(map[string]string, len(val))
+		for k := range val {
+			enc := append(appendHead(nil, majorText, uint64(len(k))), k...)
+			keys = append(keys, enc)
+			byKey[string(enc)] = k
+		}
+		sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		buf = appendHead(buf, majorMap, uint64(len(val)))
+		for _, enc := range keys {
+			buf = append(buf, enc...)
+			var err error
+			if buf, err = appendValue(buf, val[byKey[string(enc)]]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("invalid value %T", val)
+	}
+}
+
+func appendInt(buf []byte, n int64) []byte {
+	if n >= 0 {
+		return appendHead(buf, majorUint, uint64(n))
+	}
+	return appendHead(buf, majorNegInt, uint64(-1-n))
+}
+
+// appendBignum writes n as a plain integer when its magnitude fits 64 bits,
+// and otherwise as tag 2, or tag 3 holding -1 - n, around the big-endian bytes.
+func appendBignum(buf []byte, n *big.Int) []byte {
+	major, tag, mag := byte(majorUint), uint64(tagPosBignum), n
+	if n.Sign() < 0 {
+		major, tag, mag = majorNegInt, tagNegBignum, new(big.Int).Not(n)
+	}
+	if mag.IsUint64() {
+		return appendHead(buf, major, mag.Uint64())
+	}
+	b := mag.Bytes()
+	buf = appendHead(buf, majorTag, tag)
+	return append(appendHead(buf, majorBytes, uint64(len(b))), b...)
+}
+
+// appendFloat writes f as a half, single or double precision float, whichever
+// is shortest without losing precision.
+func appendFloat(buf []byte, f float64) []byte {
+	f32 := float32(f)
+	if float64(f32) != f && !math.IsNaN(f) {
+		bits := math.Float64bits(f)
+		buf = append(buf, majorSimple<<5|27)
+		for shift := 56; shift >= 0; shift -= 8 {
+			buf = append(buf, byte(bits>>shift))
+		}
+		return buf
+	}
+	if h, ok := toFloat16(f32); ok {
+		return append(buf, majorSimple<<5|25, byte(h>>8), byte(h))
+	}
+	bits := math.Float32bits(f32)
+	return append(buf, majorSimple<<5|26, byte(bits>>24), byte(bits>>16), byte(bits>>8), byte(bits))
+}
+
+func toFloat16(f float32) (uint16, bool) {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127
+	mant := bits & 0x7fffff
+	switch {
+	case bits&0x7fffffff == 0:
+		return sign, true
+	case exp == 128:
+		if mant == 0 {
+			return sign | 0x7c00, true
+		}
+		return 0x7e00, true
+	case exp >= -14 && exp <= 15:
+		if mant&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(exp+15)<<10 | uint16(mant>>13), true
+	case exp >= -24 && exp < -14:
+		m := 1<<23 | mant
+		shift := uint(-exp - 1)
+		if m&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(m>>shift), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,211 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mntwlds/jchain"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Vectors from RFC 8949 appendix A.
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		hex, json string
+	}{
+		{"00", `0`},
+		{"17", `23`},
+		{"1818", `24`},
+		{"1903e8", `1000`},
+		{"1bffffffffffffffff", `18446744073709551615`},
+		{"c249010000000000000000", `18446744073709551616`},
+		{"c2581a0100000000000000000000000000000000000000000000000001", `1606938044258990275541962092341162602522202993782792835301377`},
+		{"3bffffffffffffffff", `-18446744073709551616`},
+		{"3b8000000000000000", `-9223372036854775809`},
+		{"c349010000000000000000", `-18446744073709551617`},
+		{"c348ffffffffffffffff", `-18446744073709551616`},
+		{"c24101", `1`},
+		{"c34100", `-1`},
+		{"20", `-1`},
+		{"3903e7", `-1000`},
+		{"f90000", `0.0`},
+		{"f93c00", `1.0`},
+		{"f93e00", `1.5`},
+		{"f97bff", `65504.0`},
+		{"fa47c35000", `100000.0`},
+		{"f90001", `5.960464477539063e-8`},
+		{"fb3ff199999999999a", `1.1`},
+		{"fbc010666666666666", `-4.1`},
+		{"f4", `false`},
+		{"f5", `true`},
+		{"f6", `null`},
+		{"f7", `null`},
+		{"c074323031332d30332d32315432303a30343a30305a", `"2013-03-21T20:04:00Z"`},
+		{"c11a514b67b0", `"2013-03-21T20:04:00Z"`},
+		{"c1fb41d452d9ec200000", `"2013-03-21T20:04:00.5Z"`},
+		{"d74401020304", `"AQIDBA=="`},
+		{"4401020304", `"AQIDBA=="`},
+		{"6449455446", `"IETF"`},
+		{"62c3bc", `"ü"`},
+		{"83010203", `[1, 2, 3]`},
+		{"8301820203820405", `[1, [2, 3], [4, 5]]`},
+		{"a201020304", `{"1": 2, "3": 4}`},
+		{"a26161016162820203", `{"a": 1, "b": [2, 3]}`},
+		{"5f42010243030405ff", `"AQIDBAU="`},
+		{"7f657374726561646d696e67ff", `"streaming"`},
+		{"9fff", `[]`},
+		{"9f018202039f0405ffff", `[1, [2, 3], [4, 5]]`},
+		{"83018202039f0405ff", `[1, [2, 3], [4, 5]]`},
+		{"bf61610161629f0203ffff", `{"a": 1, "b": [2, 3]}`},
+		{"bf6346756ef563416d7421ff", `{"Fun": true, "Amt": -2}`},
+	}
+	for _, tt := range tests {
+		got := Decode(unhex(t, tt.hex))
+		if err := got.Error(); err != nil {
+			t.Errorf("%s: %v", tt.hex, err)
+			continue
+		}
+		want := jchain.ParseWithOptions(tt.json, jchain.ParseOptions{BigNumbers: true})
+		if !got.Equal(want) || got.Kind() != want.Kind() {
+			t.Errorf("%s: got %v (%v), want %s", tt.hex, got, got.Kind(), tt.json)
+		}
+	}
+
+	n, err := Decode(unhex(t, "c349010000000000000000")).BigInt()
+	if err != nil || n.String() != "-18446744073709551617" {
+		t.Errorf("expected the bignum to read back exactly, got %v, %v", n, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"":               "unexpected end of input at offset 0",
+		"1c":             "invalid additional information 28 at offset 0",
+		"1f":             "invalid additional information 31 at offset 0",
+		"ff":             "unexpected break at offset 0",
+		"8201ff":         "unexpected break at offset 2",
+		"6261":           "unexpected end of input at offset 2",
+		"61ff":           "invalid UTF-8 in string at offset 0",
+		"5f6161ff":       "invalid chunk in indefinite-length string at offset 1",
+		"0000":           "unexpected data after value at offset 1",
+		"a2616101616102": "duplicate key a at offset 4",
+		"a1f601":         "unsupported map key type <nil> at offset 1",
+		"f8ff":           "unsupported simple value 255 at offset 0",
+		"c06161":         "invalid date/time \"a\" at offset 0",
+		"c2f6":           "invalid content for tag 2 at offset 0",
+		"818181f6":       "maximum depth exceeded at offset 2",
+		"9f9f9f":         "maximum depth exceeded at offset 2",
+		"f97e00":         "unsupported float NaN",
+	}
+	for in, msg := range tests {
+		err := DecodeWithOptions(unhex(t, in), DecodeOptions{MaxDepth: 2}).Error()
+		if err == nil || err.Error() != msg {
+			t.Errorf("%q: expected %q, got %v", in, msg, err)
+		}
+	}
+}
+
+func TestDecodeTag(t *testing.T) {
+	// Tag 32 (URI) without a handler keeps its content.
+	uri := "d820" + "6b" + hex.EncodeToString([]byte("http://a.b/"))
+	if s, err := Decode(unhex(t, uri)).String(); err != nil || s != "http://a.b/" {
+		t.Errorf("got %q, %v", s, err)
+	}
+
+	opts := DecodeOptions{Tag: func(number uint64, content *jchain.Value) (any, error) {
+		if number != 32 {
+			return nil, fmt.Errorf("unknown tag %d", number)
+		}
+		s, err := content.String()
+		return map[string]any{"uri": s}, err
+	}}
+	got := DecodeWithOptions(unhex(t, "81"+uri), opts)
+	if !got.Equal(jchain.Parse(`[{"uri": "http://a.b/"}]`)) {
+		t.Errorf("got %v, %v", got, got.Error())
+	}
+	if err := DecodeWithOptions(unhex(t, "d82101"), opts).Error(); err == nil || err.Error() != "unknown tag 33" {
+		t.Errorf("expected handler error, got %v", err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		json, hex string
+	}{
+		{`0`, "00"},
+		{`24`, "1818"},
+		{`1000000`, "1a000f4240"},
+		{`1000000000000`, "1b000000e8d4a51000"},
+		{`18446744073709551615`, "1bffffffffffffffff"},
+		{`-1000`, "3903e7"},
+		{`-9223372036854775808`, "3b7fffffffffffffff"},
+		{`-18446744073709551616`, "3bffffffffffffffff"},
+		{`18446744073709551616`, "c249010000000000000000"},
+		{`-18446744073709551617`, "c349010000000000000000"},
+		{`0.0`, "f90000"},
+		{`-0.0`, "f98000"},
+		{`1.5`, "f93e00"},
+		{`65504.0`, "f97bff"},
+		{`100000.0`, "fa47c35000"},
+		{`5.960464477539063e-8`, "f90001"},
+		{`1.1`, "fb3ff199999999999a"},
+		{`1.0e300`, "fb7e37e43c8800759c"},
+		{`"IETF"`, "6449455446"},
+		{`[1, [2, 3]]`, "8201820203"},
+		{`{"b": 1, "aa": 2, "a": 3}`, "a361610361620162616102"},
+		{`null`, "f6"},
+		{`true`, "f5"},
+	}
+	for _, tt := range tests {
+		v := jchain.ParseWithOptions(tt.json, jchain.ParseOptions{BigNumbers: true})
+		got, err := Encode(v)
+		if err != nil {
+			t.Errorf("%s: %v", tt.json, err)
+			continue
+		}
+		if want := unhex(t, tt.hex); !bytes.Equal(got, want) {
+			t.Errorf("%s: got %x, want %x", tt.json, got, want)
+		}
+		if back := Decode(got); !back.Equal(v) {
+			t.Errorf("%s: round trip gave %v", tt.json, back)
+		}
+	}
+
+	if _, err := Encode(jchain.Parse(`[`)); err == nil {
+		t.Error("expected the chain error to be returned")
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	nested := func(n int) string {
+		return strings.Repeat("[", n) + strings.Repeat("]", n)
+	}
+	v := jchain.Parse(nested(1000))
+	data, err := Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back := Decode(data); !back.Equal(v) {
+		t.Errorf("round trip of 1000 levels gave %v", back.Error())
+	}
+	deeper := append(bytes.Repeat([]byte{0x81}, 1000), 0x80)
+	if err := Decode(deeper).Error(); err == nil || err.Error() != "maximum depth exceeded at offset 1000" {
+		t.Errorf("expected Decode to stop at 1000 levels, got %v", err)
+	}
+	for _, max := range []int{0, 5000} {
+		if err := DecodeWithOptions(deeper, DecodeOptions{MaxDepth: max}).Error(); err != nil {
+			t.Errorf("MaxDepth %d: %v", max, err)
+		}
+	}
+}
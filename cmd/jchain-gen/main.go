@@ -0,0 +1,83 @@
+// Command jchain-gen prints Go struct definitions for sample JSON documents.
+//
+// Usage:
+//
+//	jchain-gen [-name Root] [-pkg main] [-type path=Name ...] [file ...]
+//
+// Every file is treated as one sample; without files a single sample is read
+// from standard input.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mntwlds/jchain"
+)
+
+type typeNames map[string]string
+
+func (t typeNames) String() string {
+	pairs := make([]string, 0, len(t))
+	for path, name := range t {
+		pairs = append(pairs, path+"="+name)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (t typeNames) Set(s string) error {
+	path, name, ok := strings.Cut(s, "=")
+	if !ok || path == "" || name == "" {
+		return fmt.Errorf("expected path=Name, got %q", s)
+	}
+	t[path] = name
+	return nil
+}
+
+func main() {
+	names := make(typeNames)
+	name := flag.String("name", "Root", "name of the root type")
+	pkg := flag.String("pkg", "main", "package clause to emit, empty for none")
+	flag.Var(names, "type", "name the type at a dotted key `path=Name` (repeatable)")
+	flag.Parse()
+
+	samples, err := readSamples(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "jchain-gen:", err)
+		os.Exit(1)
+	}
+
+	src, err := jchain.GenerateStructs(*name, jchain.StructOptions{Package: *pkg, Names: names}, samples...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "jchain-gen:", err)
+		os.Exit(1)
+	}
+	fmt.Print(src)
+}
+
+func readSamples(files []string) ([]*jchain.Value, error) {
+	if len(files) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return []*jchain.Value{jchain.Parse(string(data))}, nil
+	}
+
+	samples := make([]*jchain.Value, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		sample := jchain.Parse(string(data))
+		if err := sample.Error(); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
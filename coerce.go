@@ -0,0 +1,248 @@
+package jchain
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Coercer reads a value leniently. It is returned by Value.Coerce.
+type Coercer struct {
+	v *Value
+}
+
+// Coerce returns accessors that convert between kinds where the intent is
+// clear, for APIs that are loose about types:
+//
+//   - strings holding a JSON number are read as that number;
+//   - floats with no fractional part are read as integers;
+//   - integers are read as floats;
+//   - "true", "false", "yes", "no", "1" and "0" (in any case), and the
+//     integers 1 and 0, are read as booleans;
+//   - strings, numbers and booleans are read as strings.
+//
+// Once converted, the same range checks as Int8…Uint64 and Float32 apply.
+func (v *Value) Coerce() Coercer {
+	return Coercer{v: v}
+}
+
+// number returns the value as an Int or Float, reading numeric strings.
+func (c Coercer) number() (*Value, error) {
+	v := c.v
+	if v.err != nil {
+		return nil, v.err
+	}
+	switch v.kind {
+	case Int, Float:
+		return v, nil
+	case String:
+		s, _ := v.data.(string)
+		n := Parse(strings.TrimSpace(s))
+		if n.err == nil && (n.kind == Int || n.kind == Float) {
+			return n, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c Coercer) integer() (*Value, error) {
+	n, err := c.number()
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, fmt.Errorf("not int")
+	}
+	if n.kind == Int {
+		return n, nil
+	}
+	f, _ := n.data.(float64)
+	switch {
+	case math.IsInf(f, 0) || math.IsNaN(f) || f != math.Trunc(f):
+		return nil, fmt.Errorf("not int")
+	case f >= -(1<<63) && f < 1<<63:
+		return &Value{kind: Int, data: int64(f)}, nil
+	case f >= 0 && f < 1<<64:
+		return &Value{kind: Int, data: uint64(f)}, nil
+	default:
+		return nil, fmt.Errorf("out of range")
+	}
+}
+
+func (c Coercer) float() (*Value, error) {
+	n, err := c.number()
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, fmt.Errorf("not float")
+	}
+	switch val := n.data.(type) {
+	case int:
+		return &Value{kind: Float, data: float64(val)}, nil
+	case int64:
+		return &Value{kind: Float, data: float64(val)}, nil
+	case uint64:
+		return &Value{kind: Float, data: float64(val)}, nil
+	case *big.Int:
+		f, _ := bigFloat(val)
+		return &Value{kind: Float, data: f}, nil
+	}
+	return n, nil
+}
+
+func (c Coercer) Int64() (int64, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Int64()
+}
+
+func (c Coercer) Int32() (int32, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Int32()
+}
+
+func (c Coercer) Int16() (int16, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Int16()
+}
+
+func (c Coercer) Int8() (int8, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Int8()
+}
+
+func (c Coercer) Int() (int, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Int()
+}
+
+func (c Coercer) Uint64() (uint64, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Uint64()
+}
+
+func (c Coercer) Uint32() (uint32, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Uint32()
+}
+
+func (c Coercer) Uint16() (uint16, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Uint16()
+}
+
+func (c Coercer) Uint8() (uint8, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Uint8()
+}
+
+func (c Coercer) Uint() (uint, error) {
+	v, err := c.integer()
+	if err != nil {
+		return 0, err
+	}
+	return v.Uint()
+}
+
+func (c Coercer) Float64() (float64, error) {
+	v, err := c.float()
+	if err != nil {
+		return 0, err
+	}
+	return v.Float64()
+}
+
+func (c Coercer) Float32() (float32, error) {
+	v, err := c.float()
+	if err != nil {
+		return 0, err
+	}
+	return v.Float32()
+}
+
+func (c Coercer) Bool() (bool, error) {
+	v := c.v
+	if v.err != nil {
+		return false, v.err
+	}
+	switch val := v.data.(type) {
+	case bool:
+		return val, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(val)) {
+		case "true", "yes", "1":
+			return true, nil
+		case "false", "no", "0":
+			return false, nil
+		}
+	case int:
+		if val == 0 || val == 1 {
+			return val == 1, nil
+		}
+	case int64:
+		if val == 0 || val == 1 {
+			return val == 1, nil
+		}
+	case uint64:
+		if val == 0 || val == 1 {
+			return val == 1, nil
+		}
+	}
+	return false, fmt.Errorf("not boolean")
+}
+
+// String formats numbers in the shortest form that reads back the same, and
+// booleans as "true" or "false".
+func (c Coercer) String() (string, error) {
+	v := c.v
+	if v.err != nil {
+		return "", v.err
+	}
+	switch val := v.data.(type) {
+	case string:
+		return val, nil
+	case int:
+		return strconv.Itoa(val), nil
+	case int64:
+		return strconv.FormatInt(val, 10), nil
+	case uint64:
+		return strconv.FormatUint(val, 10), nil
+	case *big.Int:
+		return val.String(), nil
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	default:
+		return "", fmt.Errorf("not string")
+	}
+}
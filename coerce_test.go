@@ -0,0 +1,103 @@
+package jchain
+
+import (
+	"testing"
+)
+
+func TestCoerceNumbers(t *testing.T) {
+	ints := map[string]int64{
+		`42`:       42,
+		`"42"`:     42,
+		`" -7 "`:   -7,
+		`3.0`:      3,
+		`"1e3"`:    1000,
+		`-0.0`:     0,
+		`"12.000"`: 12,
+	}
+	for json, want := range ints {
+		got, err := Parse(json).Coerce().Int64()
+		if err != nil || got != want {
+			t.Errorf("%s: got %d, %v, want %d", json, got, err, want)
+		}
+	}
+
+	intErrs := map[string]string{
+		`3.5`:     "not int",
+		`"3.5"`:   "not int",
+		`"abc"`:   "not int",
+		`"0x10"`:  "not int",
+		`true`:    "not int",
+		`1e300`:   "out of range",
+		`"1e300"`: "out of range",
+	}
+	for json, msg := range intErrs {
+		if _, err := Parse(json).Coerce().Int64(); err == nil || err.Error() != msg {
+			t.Errorf("%s: expected %q, got %v", json, msg, err)
+		}
+	}
+
+	if _, err := Parse(`"300"`).Coerce().Int8(); err == nil || err.Error() != "out of range" {
+		t.Errorf("expected Int8 range check, got %v", err)
+	}
+	if _, err := Parse(`-1.0`).Coerce().Uint(); err == nil {
+		t.Error("expected negative value to be rejected by Uint")
+	}
+	if got, err := Parse(`"18446744073709551615"`).Coerce().Uint64(); err != nil || got != 18446744073709551615 {
+		t.Errorf("got %d, %v", got, err)
+	}
+	if got, err := Parse(`9.223372036854775808e18`).Coerce().Uint64(); err != nil || got != 1<<63 {
+		t.Errorf("got %d, %v, want 2^63", got, err)
+	}
+	if _, err := Parse(`1.8446744073709551616e19`).Coerce().Uint64(); err == nil || err.Error() != "out of range" {
+		t.Errorf("expected 2^64 to be out of range, got %v", err)
+	}
+
+	floats := map[string]float64{`1.5`: 1.5, `2`: 2, `"2.25"`: 2.25, `" 10 "`: 10}
+	for json, want := range floats {
+		got, err := Parse(json).Coerce().Float64()
+		if err != nil || got != want {
+			t.Errorf("%s: got %v, %v, want %v", json, got, err, want)
+		}
+	}
+	if _, err := Parse(`"1e300"`).Coerce().Float32(); err == nil || err.Error() != "out of range" {
+		t.Errorf("expected Float32 range check, got %v", err)
+	}
+	if _, err := Parse(`null`).Coerce().Float64(); err == nil || err.Error() != "not float" {
+		t.Errorf("expected not float, got %v", err)
+	}
+}
+
+func TestCoerceBoolAndString(t *testing.T) {
+	bools := map[string]bool{
+		`true`: true, `"TRUE"`: true, `"yes"`: true, `"1"`: true, `1`: true,
+		`false`: false, `"False"`: false, `"no"`: false, `"0"`: false, `0`: false,
+	}
+	for json, want := range bools {
+		got, err := Parse(json).Coerce().Bool()
+		if err != nil || got != want {
+			t.Errorf("%s: got %v, %v, want %v", json, got, err, want)
+		}
+	}
+	for _, json := range []string{`"maybe"`, `2`, `1.0`, `null`, `18446744073709551615`, `9223372036854775808`} {
+		if _, err := Parse(json).Coerce().Bool(); err == nil || err.Error() != "not boolean" {
+			t.Errorf("%s: expected not boolean, got %v", json, err)
+		}
+	}
+
+	strs := map[string]string{`"x"`: "x", `42`: "42", `1.5`: "1.5", `2.0`: "2", `false`: "false", `18446744073709551615`: "18446744073709551615"}
+	for json, want := range strs {
+		got, err := Parse(json).Coerce().String()
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", json, got, err, want)
+		}
+	}
+	for _, json := range []string{`null`, `[]`, `{}`} {
+		if _, err := Parse(json).Coerce().String(); err == nil || err.Error() != "not string" {
+			t.Errorf("%s: expected not string, got %v", json, err)
+		}
+	}
+
+	if _, err := Parse(`{}`).Get("missing").Coerce().Int(); err == nil || err.Error() != "object doesnt have that key" {
+		t.Errorf("expected chain error, got %v", err)
+	}
+}
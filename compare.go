@@ -0,0 +1,378 @@
+package jchain
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+type ChangeType int
+
+const (
+	ChangeAdded ChangeType = iota
+	ChangeRemoved
+	// ChangeModified is a different value of the same kind. Numbers of
+	// either kind count as the same kind.
+	ChangeModified
+	// ChangeTypeChanged is a value replaced by one of another kind.
+	ChangeTypeChanged
+)
+
+func (c ChangeType) String() string {
+	switch c {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "changed"
+	case ChangeTypeChanged:
+		return "type changed"
+	default:
+		return "invalid"
+	}
+}
+
+// Change describes one difference. Path is a JSON Pointer, except that
+// elements of arrays compared by key appear as "field=value" tokens. Old is
+// nil for additions and New is nil for removals.
+type Change struct {
+	Path string
+	Type ChangeType
+	Old  *Value
+	New  *Value
+}
+
+type CompareOptions struct {
+	// IgnorePaths lists JSON Pointers whose subtrees are not compared. A "*"
+	// token matches any key or index.
+	IgnorePaths []string
+	// ArrayKeys treats the arrays at the given JSON Pointers as unordered
+	// sets, matching elements by the named id field. Elements appear in
+	// paths as field=id with the id in JSON text, such as id=1 or id="a".
+	ArrayKeys map[string]string
+	// FloatTolerance is the largest difference between two numbers that is
+	// still considered equal when either of them is a Float, so that with a
+	// positive tolerance 1 and 1.0 match. Zero compares numbers exactly, as
+	// Equal does, and 1 and 1.0 differ.
+	FloatTolerance float64
+}
+
+type Report struct {
+	Changes []Change
+}
+
+type comparer struct {
+	ignore    [][]string
+	arrayKeys []arrayKey
+	tolerance float64
+	changes   []Change
+}
+
+type arrayKey struct {
+	path  []string
+	field string
+}
+
+// Compare lists the differences between a and b. A Report is not a Value, so
+// like Hash and Canonical it is returned with an error rather than carrying
+// one: the error of a or b, or that of an invalid pointer in opts.
+func Compare(a, b *Value, opts CompareOptions) (*Report, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	c := &comparer{tolerance: opts.FloatTolerance}
+	for _, ptr := range opts.IgnorePaths {
+		tokens, err := parsePointer(ptr)
+		if err != nil {
+			return nil, err
+		}
+		c.ignore = append(c.ignore, tokens)
+	}
+	for ptr, field := range opts.ArrayKeys {
+		tokens, err := parsePointer(ptr)
+		if err != nil {
+			return nil, err
+		}
+		c.arrayKeys = append(c.arrayKeys, arrayKey{path: tokens, field: field})
+	}
+
+	c.compare(nil, a.data, b.data)
+	return &Report{Changes: c.changes}, nil
+}
+
+func matchPath(pattern, path []string, prefix bool) bool {
+	if len(path) < len(pattern) || (!prefix && len(path) != len(pattern)) {
+		return false
+	}
+	for i, token := range pattern {
+		if token != "*" && token != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *comparer) ignored(path []string) bool {
+	for _, pattern := range c.ignore {
+		if matchPath(pattern, path, true) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *comparer) keyField(path []string) string {
+	for _, key := range c.arrayKeys {
+		if matchPath(key.path, path, false) {
+			return key.field
+		}
+	}
+	return ""
+}
+
+func (c *comparer) add(path []string, typ ChangeType, before, after any, hasBefore, hasAfter bool) {
+	change := Change{Path: formatPointer(path), Type: typ}
+	if hasBefore {
+		change.Old = &Value{kind: getKind(before), data: before}
+	}
+	if hasAfter {
+		change.New = &Value{kind: getKind(after), data: after}
+	}
+	c.changes = append(c.changes, change)
+}
+
+func (c *comparer) compare(path []string, a, b any) {
+	if c.ignored(path) {
+		return
+	}
+
+	ka, kb := getKind(a), getKind(b)
+	if isNumber(ka) && isNumber(kb) {
+		if !c.equalNumbers(a, b) {
+			c.add(path, ChangeModified, a, b, true, true)
+		}
+		return
+	}
+	if ka != kb {
+		c.add(path, ChangeTypeChanged, a, b, true, true)
+		return
+	}
+
+	switch a := a.(type) {
+	case map[string]any:
+		c.compareObjects(path, a, b.(map[string]any))
+	case []any:
+		if field := c.keyField(path); field != "" && c.compareKeyed(path, field, a, b.([]any)) {
+			return
+		}
+		c.compareArrays(path, a, b.([]any))
+	default:
+		if a != b {
+			c.add(path, ChangeModified, a, b, true, true)
+		}
+	}
+}
+
+func isNumber(k Kind) bool {
+	return k == Int || k == Float
+}
+
+func (c *comparer) equalNumbers(a, b any) bool {
+	_, fa := a.(float64)
+	_, fb := b.(float64)
+	if c.tolerance == 0 || (!fa && !fb) {
+		return equalData(a, b)
+	}
+	return math.Abs(toFloat(a)-toFloat(b)) <= c.tolerance
+}
+
+func toFloat(val any) float64 {
+	switch val := val.(type) {
+	case int64:
+		return float64(val)
+	case uint64:
+		return float64(val)
+	case *big.Int:
+		f, _ := bigFloat(val)
+		return f
+	case float64:
+		return val
+	}
+	return math.NaN()
+}
+
+func (c *comparer) compareObjects(path []string, a, b map[string]any) {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child := appendPath(path, key)
+		aVal, inA := a[key]
+		bVal, inB := b[key]
+		switch {
+		case c.ignored(child):
+		case !inB:
+			c.add(child, ChangeRemoved, aVal, nil, true, false)
+		case !inA:
+			c.add(child, ChangeAdded, nil, bVal, false, true)
+		default:
+			c.compare(child, aVal, bVal)
+		}
+	}
+}
+
+func (c *comparer) compareArrays(path []string, a, b []any) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		child := appendPath(path, fmt.Sprint(i))
+		switch {
+		case c.ignored(child):
+		case i >= len(b):
+			c.add(child, ChangeRemoved, a[i], nil, true, false)
+		case i >= len(a):
+			c.add(child, ChangeAdded, nil, b[i], false, true)
+		default:
+			c.compare(child, a[i], b[i])
+		}
+	}
+}
+
+// compareKeyed matches array elements by the id field. It reports false,
+// leaving the arrays to be compared by position, when some element has no
+// usable id or ids repeat.
+func (c *comparer) compareKeyed(path []string, field string, a, b []any) bool {
+	aIDs, ok := keyedElements(a, field)
+	if !ok {
+		return false
+	}
+	bIDs, ok := keyedElements(b, field)
+	if !ok {
+		return false
+	}
+
+	for _, elem := range a {
+		id := elementID(elem, field)
+		child := appendPath(path, field+"="+id)
+		if c.ignored(child) {
+			continue
+		}
+		if other, ok := bIDs[id]; ok {
+			c.compare(child, elem, other)
+		} else {
+			c.add(child, ChangeRemoved, elem, nil, true, false)
+		}
+	}
+	for _, elem := range b {
+		id := elementID(elem, field)
+		child := appendPath(path, field+"="+id)
+		if _, ok := aIDs[id]; !ok && !c.ignored(child) {
+			c.add(child, ChangeAdded, nil, elem, false, true)
+		}
+	}
+	return true
+}
+
+func keyedElements(arr []any, field string) (map[string]any, bool) {
+	res := make(map[string]any, len(arr))
+	for _, elem := range arr {
+		id := elementID(elem, field)
+		if id == "" {
+			return nil, false
+		}
+		if _, dup := res[id]; dup {
+			return nil, false
+		}
+		res[id] = elem
+	}
+	return res, true
+}
+
+func elementID(elem any, field string) string {
+	obj, ok := elem.(map[string]any)
+	if !ok {
+		return ""
+	}
+	id, ok := obj[field]
+	if !ok {
+		return ""
+	}
+	switch id.(type) {
+	case map[string]any, []any:
+		return ""
+	}
+	// The JSON text keeps the type, so "1" and 1 are different ids.
+	return encodeText(id)
+}
+
+func (r *Report) Empty() bool {
+	return len(r.Changes) == 0
+}
+
+// Text renders the report in a unified diff style, one hunk per change.
+func (r *Report) Text() string {
+	if r.Empty() {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("--- a\n+++ b\n")
+	for _, change := range r.Changes {
+		path := change.Path
+		if path == "" {
+			path = "(root)"
+		}
+		fmt.Fprintf(&sb, "@@ %s (%s) @@\n", path, change.Type)
+		if change.Old != nil {
+			sb.WriteString("- ")
+			sb.WriteString(encodeText(change.Old.data))
+			sb.WriteByte('\n')
+		}
+		if change.New != nil {
+			sb.WriteString("+ ")
+			sb.WriteString(encodeText(change.New.data))
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+func encodeText(val any) string {
+	buf, err := appendJSON(nil, val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return string(buf)
+}
+
+// JSON returns the report as an array of objects with "path", "type", and,
+// where present, "old" and "new" members.
+func (r *Report) JSON() *Value {
+	changes := make([]any, 0, len(r.Changes))
+	for _, change := range r.Changes {
+		obj := map[string]any{
+			"path": change.Path,
+			"type": change.Type.String(),
+		}
+		if change.Old != nil {
+			obj["old"] = change.Old.data
+		}
+		if change.New != nil {
+			obj["new"] = change.New.data
+		}
+		changes = append(changes, obj)
+	}
+	return &Value{kind: Array, data: changes}
+}
@@ -0,0 +1,104 @@
+package jchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	a := Parse(`{
+		"name": "svc",
+		"version": 1,
+		"ratio": 0.5,
+		"meta": {"updated": "2024-01-01", "owner": "ops"},
+		"users": [{"id": 1, "name": "Alice"}, {"id": 2, "name": "Bob"}],
+		"tags": ["a", "b"]
+	}`)
+	b := Parse(`{
+		"name": "svc",
+		"version": "1",
+		"ratio": 0.5000001,
+		"meta": {"updated": "2024-02-01"},
+		"users": [{"id": 3, "name": "Carol"}, {"id": 1, "name": "Alicia"}],
+		"tags": ["a", "b", "c"]
+	}`)
+
+	report, err := Compare(a, b, CompareOptions{
+		IgnorePaths:    []string{"/meta/updated"},
+		ArrayKeys:      map[string]string{"/users": "id"},
+		FloatTolerance: 1e-3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]ChangeType{
+		"/version":         ChangeTypeChanged,
+		"/meta/owner":      ChangeRemoved,
+		"/users/id=1/name": ChangeModified,
+		"/users/id=2":      ChangeRemoved,
+		"/users/id=3":      ChangeAdded,
+		"/tags/2":          ChangeAdded,
+	}
+	if len(report.Changes) != len(want) {
+		t.Errorf("expected %d changes, got %d:\n%s", len(want), len(report.Changes), report.Text())
+	}
+	for _, change := range report.Changes {
+		if typ, ok := want[change.Path]; !ok || typ != change.Type {
+			t.Errorf("unexpected change %s (%s)", change.Path, change.Type)
+		}
+	}
+
+	text := report.Text()
+	if !strings.Contains(text, "@@ /users/id=1/name (changed) @@\n- \"Alice\"\n+ \"Alicia\"\n") {
+		t.Errorf("unexpected text report:\n%s", text)
+	}
+
+	changes := report.JSON()
+	if typ, _ := changes.Index(0).Get("type").String(); typ != "removed" {
+		t.Errorf("unexpected first change in JSON report: %v", changes.Index(0).data)
+	}
+}
+
+func TestCompareMixedIDs(t *testing.T) {
+	a := Parse(`[{"id": 1, "v": "num"}, {"id": "1", "v": "str"}]`)
+	b := Parse(`[{"id": "1", "v": "str"}, {"id": 1, "v": "num"}, {"id": "a", "v": "new"}]`)
+	report, err := Compare(a, b, CompareOptions{ArrayKeys: map[string]string{"": "id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 1 || report.Changes[0].Path != `/id="a"` || report.Changes[0].Type != ChangeAdded {
+		t.Errorf("expected only id \"a\" to be added, got:\n%s", report.Text())
+	}
+}
+
+func TestCompareNumbers(t *testing.T) {
+	a, b := Parse(`[1, 2.0, 3]`), Parse(`[1.0, 2, 3]`)
+	report, err := Compare(a, b, CompareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 2 || report.Changes[0].Path != "/0" || report.Changes[1].Path != "/1" || report.Changes[0].Type != ChangeModified {
+		t.Errorf("expected an Int and a Float to differ without a tolerance, got:\n%s", report.Text())
+	}
+	if a.Equal(b) {
+		t.Error("expected Equal to agree")
+	}
+	report, err = Compare(a, b, CompareOptions{FloatTolerance: 1e-9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Empty() {
+		t.Errorf("expected 1 and 1.0 to match with a tolerance, got:\n%s", report.Text())
+	}
+}
+
+func TestCompareEqual(t *testing.T) {
+	report, err := Compare(Parse(`{"a": [1, 2.0]}`), Parse(`{"a": [1, 2.0]}`), CompareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Empty() || report.Text() != "" {
+		t.Errorf("expected no changes, got:\n%s", report.Text())
+	}
+}
@@ -0,0 +1,15 @@
+package jchain
+
+import "github.com/mntwlds/jchain/internal/core"
+
+func init() {
+	core.Value = func(data any) any {
+		return &Value{kind: getKind(data), data: data}
+	}
+	core.Error = func(err error) any {
+		return &Value{err: err}
+	}
+	core.SyntaxError = func(input string, offset int, msg string) error {
+		return newSyntaxError(newSource("", input), input, parserError{pos: offset, msg: msg, opened: -1})
+	}
+}
@@ -0,0 +1,330 @@
+package jchain
+
+import (
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ArrayMode selects how ToCSV writes arrays.
+type ArrayMode int
+
+const (
+	// JoinArrays writes an array as one cell, its items joined with
+	// CSVOptions.ArraySeparator. Objects and arrays inside it are written as
+	// JSON.
+	JoinArrays ArrayMode = iota
+	// ExplodeArrays writes one row per array item, repeating the other
+	// columns. Objects in the array add their keys below the array's column;
+	// several arrays in one row give every combination of their items.
+	ExplodeArrays
+	// IndexArrays writes one column per item, named by its index, such as
+	// tags.0 and tags.1.
+	IndexArrays
+)
+
+type CSVOptions struct {
+	Arrays ArrayMode
+	// ArraySeparator joins array items with JoinArrays. Empty means ";".
+	ArraySeparator string
+	// Comma is the field delimiter. Zero means ','.
+	Comma rune
+	// Columns fixes the columns ToCSV writes, in order. By default it writes
+	// the union of the columns of all rows, sorted by name with array
+	// indexes in numeric order.
+	Columns []string
+	// InferTypes makes FromCSV read cells holding JSON numbers, booleans,
+	// null, arrays or objects as those values, and leave empty cells out.
+	// Otherwise every cell is a string.
+	InferTypes bool
+}
+
+// ToCSV writes an array of objects, or a single object, as CSV with a
+// header row. Nested keys become dotted column names such as user.name, with
+// dots and backslashes inside keys escaped by a backslash, and cells of rows
+// without a column are left empty.
+func ToCSV(v *Value, opts CSVOptions) (string, error) {
+	if v.err != nil {
+		return "", v.err
+	}
+	var records []any
+	switch data := v.data.(type) {
+	case []any:
+		records = data
+	case map[string]any:
+		records = []any{data}
+	default:
+		return "", fmt.Errorf("not array of objects")
+	}
+
+	var rows []map[string]any
+	for _, record := range records {
+		if _, ok := record.(map[string]any); !ok {
+			return "", fmt.Errorf("not array of objects")
+		}
+		flat, err := csvRows(record, "", opts)
+		if err != nil {
+			return "", err
+		}
+		rows = append(rows, flat...)
+	}
+
+	columns := opts.Columns
+	if columns == nil {
+		seen := map[string]bool{}
+		for _, row := range rows {
+			for col := range row {
+				if !seen[col] {
+					seen[col] = true
+					columns = append(columns, col)
+				}
+			}
+		}
+		sort.Slice(columns, func(i, j int) bool { return lessColumn(columns[i], columns[j]) })
+	}
+
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	if opts.Comma != 0 {
+		w.Comma = opts.Comma
+	}
+	if err := w.Write(columns); err != nil {
+		return "", err
+	}
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, col := range columns {
+			cell, err := csvCell(row[col])
+			if err != nil {
+				return "", err
+			}
+			record[i] = cell
+		}
+		if err := w.Write(record); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// joinColumn appends key to the column name prefix, escaping dots and
+// backslashes in key the way Flatten does, so that {"a.b": 1} and
+// {"a": {"b": 1}} get different columns.
+func joinColumn(prefix, key string) string {
+	return FlattenOptions{}.joinKey(prefix, key, prefix == "")
+}
+
+// splitColumn undoes joinColumn.
+func splitColumn(col string) []string {
+	parts, _ := FlattenOptions{}.splitKey(col)
+	keys := make([]string, len(parts))
+	for i, p := range parts {
+		keys[i] = p.key
+	}
+	return keys
+}
+
+// csvRows flattens val below the column prefix into one or more rows, more
+// than one only when exploding arrays.
+func csvRows(val any, prefix string, opts CSVOptions) ([]map[string]any, error) {
+	switch val := val.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		rows := []map[string]any{{}}
+		for _, k := range keys {
+			child, err := csvRows(val[k], joinColumn(prefix, k), opts)
+			if err != nil {
+				return nil, err
+			}
+			rows = crossRows(rows, child)
+		}
+		return rows, nil
+	case []any:
+		switch opts.Arrays {
+		case ExplodeArrays:
+			if len(val) == 0 {
+				return []map[string]any{{prefix: nil}}, nil
+			}
+			var rows []map[string]any
+			for _, item := range val {
+				child, err := csvRows(item, prefix, opts)
+				if err != nil {
+					return nil, err
+				}
+				rows = append(rows, child...)
+			}
+			return rows, nil
+		case IndexArrays:
+			rows := []map[string]any{{}}
+			for i, item := range val {
+				child, err := csvRows(item, joinColumn(prefix, strconv.Itoa(i)), opts)
+				if err != nil {
+					return nil, err
+				}
+				rows = crossRows(rows, child)
+			}
+			return rows, nil
+		default:
+			sep := opts.ArraySeparator
+			if sep == "" {
+				sep = ";"
+			}
+			cells := make([]string, len(val))
+			for i, item := range val {
+				cell, err := csvCell(item)
+				if err != nil {
+					return nil, err
+				}
+				cells[i] = cell
+			}
+			return []map[string]any{{prefix: strings.Join(cells, sep)}}, nil
+		}
+	}
+	return []map[string]any{{prefix: val}}, nil
+}
+
+// crossRows combines every row of a with every row of b.
+func crossRows(a, b []map[string]any) []map[string]any {
+	if len(b) == 1 {
+		for _, row := range a {
+			for k, v := range b[0] {
+				row[k] = v
+			}
+		}
+		return a
+	}
+	out := make([]map[string]any, 0, len(a)*len(b))
+	for _, x := range a {
+		for _, y := range b {
+			row := make(map[string]any, len(x)+len(y))
+			for k, v := range x {
+				row[k] = v
+			}
+			for k, v := range y {
+				row[k] = v
+			}
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// csvCell writes strings as they are, null as an empty cell and anything
+// else as JSON.
+func csvCell(val any) (string, error) {
+	switch val := val.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	}
+	buf, err := appendJSON(nil, val)
+	return string(buf), err
+}
+
+// lessColumn orders dotted column names part by part, comparing parts that
+// are both array indexes as numbers.
+func lessColumn(a, b string) bool {
+	pa, pb := splitColumn(a), splitColumn(b)
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		if pa[i] == pb[i] {
+			continue
+		}
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		if errA == nil && errB == nil {
+			return na < nb
+		}
+		return pa[i] < pb[i]
+	}
+	return len(pa) < len(pb)
+}
+
+// FromCSV reads CSV with a header row into an array of objects. Dotted
+// column names become nested objects, and objects whose keys are exactly
+// 0 to n-1 become arrays, reversing ToCSV with IndexArrays.
+func FromCSV(data string, opts CSVOptions) *Value {
+	r := csv.NewReader(strings.NewReader(data))
+	if opts.Comma != 0 {
+		r.Comma = opts.Comma
+	}
+	records, err := r.ReadAll()
+	if err != nil {
+		return &Value{err: err}
+	}
+	rows := []any{}
+	if len(records) == 0 {
+		return &Value{kind: Array, data: rows}
+	}
+
+	header := records[0]
+	for _, record := range records[1:] {
+		row := map[string]any{}
+		for i, col := range header {
+			cell := record[i]
+			var val any = cell
+			if opts.InferTypes {
+				if cell == "" {
+					continue
+				}
+				if parsed, err := parseJSON(cell, 1000); err == nil {
+					if _, isString := parsed.(string); !isString {
+						val = parsed
+					}
+				}
+			}
+			if !setColumn(row, splitColumn(col), val) {
+				return &Value{err: fmt.Errorf("column %s conflicts with another column", col)}
+			}
+		}
+		rows = append(rows, indexedToArrays(row))
+	}
+	return &Value{kind: Array, data: rows}
+}
+
+// setColumn stores val at path, reporting false when another column already
+// holds the path or one of its parents.
+func setColumn(obj map[string]any, path []string, val any) bool {
+	for _, key := range path[:len(path)-1] {
+		child, exists := obj[key]
+		if !exists {
+			next := map[string]any{}
+			obj[key] = next
+			obj = next
+			continue
+		}
+		next, ok := child.(map[string]any)
+		if !ok {
+			return false
+		}
+		obj = next
+	}
+	last := path[len(path)-1]
+	if _, exists := obj[last]; exists {
+		return false
+	}
+	obj[last] = val
+	return true
+}
+
+// indexedToArrays turns objects whose keys are exactly 0 to n-1 into arrays.
+func indexedToArrays(val any) any {
+	obj, ok := val.(map[string]any)
+	if !ok {
+		return val
+	}
+	for k, v := range obj {
+		obj[k] = indexedToArrays(v)
+	}
+	return indexedToArray(obj)
+}
@@ -0,0 +1,154 @@
+package jchain
+
+import "testing"
+
+const csvSample = `[
+	{"id": 1, "user": {"name": "Alice", "admin": true}, "tags": ["a", "b"]},
+	{"id": 2, "user": {"name": "Bob, Jr."}, "tags": [], "score": 9.5}
+]`
+
+func TestToCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CSVOptions
+		want string
+	}{
+		{"join", CSVOptions{},
+			"id,score,tags,user.admin,user.name\n" +
+				"1,,a;b,true,Alice\n" +
+				"2,9.5,,,\"Bob, Jr.\"\n"},
+		{"join separator", CSVOptions{ArraySeparator: "|", Comma: '\t'},
+			"id\tscore\ttags\tuser.admin\tuser.name\n" +
+				"1\t\ta|b\ttrue\tAlice\n" +
+				"2\t9.5\t\t\tBob, Jr.\n"},
+		{"explode", CSVOptions{Arrays: ExplodeArrays},
+			"id,score,tags,user.admin,user.name\n" +
+				"1,,a,true,Alice\n" +
+				"1,,b,true,Alice\n" +
+				"2,9.5,,,\"Bob, Jr.\"\n"},
+		{"index", CSVOptions{Arrays: IndexArrays},
+			"id,score,tags.0,tags.1,user.admin,user.name\n" +
+				"1,,a,b,true,Alice\n" +
+				"2,9.5,,,,\"Bob, Jr.\"\n"},
+		{"columns", CSVOptions{Columns: []string{"user.name", "id", "missing"}},
+			"user.name,id,missing\n" +
+				"Alice,1,\n" +
+				"\"Bob, Jr.\",2,\n"},
+	}
+	for _, tt := range tests {
+		got, err := ToCSV(Parse(csvSample), tt.opts)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestToCSVArrays(t *testing.T) {
+	v := Parse(`{"order": 7, "items": [{"sku": "x", "qty": 1}, {"sku": "y", "parts": [1, 2]}], "notes": [[1, 2], {"a": 1}]}`)
+
+	got, err := ToCSV(v, CSVOptions{Arrays: ExplodeArrays})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "items.parts,items.qty,items.sku,notes,notes.a,order\n" +
+		",1,x,1,,7\n,1,x,2,,7\n,1,x,,1,7\n" +
+		"1,,y,1,,7\n1,,y,2,,7\n1,,y,,1,7\n" +
+		"2,,y,1,,7\n2,,y,2,,7\n2,,y,,1,7\n"
+	if got != want {
+		t.Errorf("explode: got\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = ToCSV(v, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "items,notes,order\n" +
+		"\"{\"\"qty\"\":1,\"\"sku\"\":\"\"x\"\"};{\"\"parts\"\":[1,2],\"\"sku\"\":\"\"y\"\"}\",\"[1,2];{\"\"a\"\":1}\",7\n"
+	if got != want {
+		t.Errorf("join: got\n%s\nwant\n%s", got, want)
+	}
+
+	wide := Parse(`[{"v": [0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11]}]`)
+	got, _ = ToCSV(wide, CSVOptions{Arrays: IndexArrays})
+	if want := "v.0,v.1,v.2,v.3,v.4,v.5,v.6,v.7,v.8,v.9,v.10,v.11\n"; got[:len(want)] != want {
+		t.Errorf("expected numeric column order, got %s", got)
+	}
+
+	for _, in := range []string{`[1, 2]`, `"x"`, `[{"a": 1}, 2]`} {
+		if _, err := ToCSV(Parse(in), CSVOptions{}); err == nil || err.Error() != "not array of objects" {
+			t.Errorf("%s: expected not array of objects, got %v", in, err)
+		}
+	}
+	if _, err := ToCSV(Parse(`[`), CSVOptions{}); err == nil {
+		t.Error("expected the chain error to be returned")
+	}
+}
+
+func TestFromCSV(t *testing.T) {
+	data := "id,user.name,user.admin,tags.0,tags.1,note\n" +
+		"1,Alice,true,a,b,\"x, y\"\n" +
+		"2,Bob,,,,null\n"
+
+	got := FromCSV(data, CSVOptions{})
+	want := Parse(`[
+		{"id": "1", "user": {"name": "Alice", "admin": "true"}, "tags": ["a", "b"], "note": "x, y"},
+		{"id": "2", "user": {"name": "Bob", "admin": ""}, "tags": ["", ""], "note": "null"}
+	]`)
+	if !got.Equal(want) {
+		t.Errorf("got %v, %v", got, got.Error())
+	}
+
+	got = FromCSV(data, CSVOptions{InferTypes: true})
+	want = Parse(`[
+		{"id": 1, "user": {"name": "Alice", "admin": true}, "tags": ["a", "b"], "note": "x, y"},
+		{"id": 2, "user": {"name": "Bob"}, "note": null}
+	]`)
+	if !got.Equal(want) {
+		t.Errorf("inferred: got %v, %v", got, got.Error())
+	}
+	if k := FromCSV("n,m\n1.0,[1]\n", CSVOptions{InferTypes: true}).Index(0).Get("n").Kind(); k != Float {
+		t.Errorf("expected 1.0 to be a float, got %v", k)
+	}
+
+	// ToCSV with IndexArrays and FromCSV with InferTypes round trip.
+	orig := Parse(`[{"a": {"b": [1, {"c": false}]}, "d": "text"}, {"d": "x", "e": 2.5}]`)
+	text, err := ToCSV(orig, CSVOptions{Arrays: IndexArrays, Comma: ';'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back := FromCSV(text, CSVOptions{InferTypes: true, Comma: ';'}); !back.Equal(orig) {
+		t.Errorf("round trip gave %v from\n%s", back, text)
+	}
+
+	if rows, _ := FromCSV("a,b\n", CSVOptions{}).Array(); len(rows) != 0 {
+		t.Errorf("expected no rows, got %v", rows)
+	}
+	if err := FromCSV("a,a.b\n1,2\n", CSVOptions{}).Error(); err == nil || err.Error() != "column a.b conflicts with another column" {
+		t.Errorf("expected column conflict, got %v", err)
+	}
+	dotted := Parse(`[{"a.b": 1, "a": {"b": 2}, "c\\d": 3}]`)
+	text, err = ToCSV(dotted, CSVOptions{})
+	if want := "a.b,a\\.b,c\\\\d\n2,1,3\n"; err != nil || text != want {
+		t.Errorf("expected escaped columns %q, got %q, %v", want, text, err)
+	}
+	if back := FromCSV(text, CSVOptions{InferTypes: true}); !back.Equal(dotted) {
+		t.Errorf("escaped round trip gave %v", back)
+	}
+	// Headers written with escaped dots read back to the same keys and
+	// are written again unchanged.
+	header := "a.c,a\\.b,d\\.e.f,g\\\\h\n1,2,3,4\n"
+	read := FromCSV(header, CSVOptions{})
+	if !read.Equal(Parse(`[{"a": {"c": "1"}, "a.b": "2", "d.e": {"f": "3"}, "g\\h": "4"}]`)) {
+		t.Errorf("escaped headers read as %v, %v", read, read.Error())
+	}
+	if text, err := ToCSV(read, CSVOptions{}); err != nil || text != header {
+		t.Errorf("escaped headers written back as %q, %v", text, err)
+	}
+	if err := FromCSV("a,b\n1\n", CSVOptions{}).Error(); err == nil {
+		t.Error("expected error for a short record")
+	}
+}
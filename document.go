@@ -0,0 +1,255 @@
+package jchain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Document is a parsed JSON text that can be edited in place. Set and Delete
+// rewrite only the bytes of the value or member they touch, so formatting,
+// key order and, in relaxed mode, comments elsewhere are kept byte for byte.
+type Document struct {
+	src  string
+	opts ParseOptions
+	data any
+	root *span
+}
+
+func ParseDocument(json string, opts ParseOptions) (*Document, error) {
+	d := &Document{opts: opts}
+	if err := d.reset(json); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *Document) reset(src string) error {
+	p := newParser(src, d.opts)
+	p.track = true
+	data, err := p.parse()
+	if err != nil {
+		return err
+	}
+	d.src, d.data, d.root = src, data, p.root
+	return nil
+}
+
+func (d *Document) String() string {
+	return d.src
+}
+
+func (d *Document) Value() *Value {
+	return &Value{kind: getKind(d.data), data: d.data}
+}
+
+// Set replaces the value at the JSON Pointer ptr with val. A missing object
+// member is appended after the last member, and "-" or the array length
+// appends to an array.
+func (d *Document) Set(ptr string, val *Value) error {
+	if val.err != nil {
+		return val.err
+	}
+	tokens, err := parsePointer(ptr)
+	if err != nil {
+		return err
+	}
+	text, err := appendJSON(nil, val.data)
+	if err != nil {
+		return err
+	}
+
+	if node, _, err := d.find(tokens); err == nil {
+		return d.splice(node.start, node.end, string(text))
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("invalid pointer %q", ptr)
+	}
+	parent, _, err := d.find(tokens[:len(tokens)-1])
+	if err != nil {
+		return err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch d.src[parent.start] {
+	case '{':
+		return d.insert(parent, string(appendString(nil, last)), string(text))
+	case '[':
+		if last != "-" && last != fmt.Sprint(len(parent.children)) {
+			return fmt.Errorf("index out of range")
+		}
+		return d.insert(parent, "", string(text))
+	default:
+		return fmt.Errorf("cannot index %s with %q", getKind(d.lookup(tokens[:len(tokens)-1])), last)
+	}
+}
+
+// Delete removes the object member or array element at ptr, together with
+// the comma and whitespace that separated it from its neighbour and a comment
+// following it on the same line. Comments on the lines around it are kept.
+func (d *Document) Delete(ptr string) error {
+	tokens, err := parsePointer(ptr)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("cannot delete the document root")
+	}
+	node, parent, err := d.find(tokens)
+	if err != nil {
+		return err
+	}
+
+	idx := 0
+	for idx < len(parent.children) && parent.children[idx] != node {
+		idx++
+	}
+	start, end := memberStart(node), node.end
+	comma := d.skip(end)
+	hasComma := comma < len(d.src) && d.src[comma] == ','
+	if hasComma {
+		end = comma + 1
+	}
+	end = d.lineComment(end)
+
+	switch {
+	case idx+1 < len(parent.children):
+		for end < len(d.src) && isSpace(d.src[end]) {
+			end++
+		}
+		return d.splice(start, end, "")
+	case idx > 0:
+		// Drop the whitespace before the member and, unless it has a trailing
+		// comma of its own, the comma after its predecessor.
+		prev := d.skip(parent.children[idx-1].end)
+		for start > prev+1 && isSpace(d.src[start-1]) {
+			start--
+		}
+		if hasComma {
+			return d.splice(start, end, "")
+		}
+		return d.reset(d.src[:prev] + d.src[prev+1:start] + d.src[end:])
+	default:
+		// Drop the line the member was on; a container left with nothing but
+		// whitespace collapses to {} or [].
+		for start > parent.start+1 && isSpace(d.src[start-1]) {
+			start--
+		}
+		if start == parent.start+1 {
+			for end < len(d.src) && isSpace(d.src[end]) {
+				end++
+			}
+		}
+		return d.splice(start, end, "")
+	}
+}
+
+// lineComment returns the end of a comment that follows i on the same line,
+// or i when there is none.
+func (d *Document) lineComment(i int) int {
+	j := i
+	for j < len(d.src) && (d.src[j] == ' ' || d.src[j] == '\t') {
+		j++
+	}
+	switch {
+	case strings.HasPrefix(d.src[j:], "//"):
+		if end := strings.IndexByte(d.src[j:], '\n'); end >= 0 {
+			return j + end
+		}
+		return len(d.src)
+	case strings.HasPrefix(d.src[j:], "/*"):
+		if end := strings.Index(d.src[j+2:], "*/"); end >= 0 && !strings.Contains(d.src[j:j+2+end], "\n") {
+			return j + end + 4
+		}
+	}
+	return i
+}
+
+func (d *Document) lookup(tokens []string) any {
+	val, _ := lookupPointer(d.data, tokens)
+	return val
+}
+
+// find returns the span at tokens and the span of its parent container.
+func (d *Document) find(tokens []string) (*span, *span, error) {
+	node, parent := d.root, (*span)(nil)
+	for i, token := range tokens {
+		var child *span
+		switch d.src[node.start] {
+		case '{':
+			for _, member := range node.children {
+				if member.key == token {
+					child = member
+					break
+				}
+			}
+			if child == nil {
+				return nil, nil, fmt.Errorf("object doesnt have key %q", token)
+			}
+		case '[':
+			idx, err := arrayIndex(token, len(node.children))
+			if err != nil {
+				return nil, nil, err
+			}
+			child = node.children[idx]
+		default:
+			return nil, nil, fmt.Errorf("cannot index %s with %q", getKind(d.lookup(tokens[:i])), token)
+		}
+		node, parent = child, node
+	}
+	return node, parent, nil
+}
+
+// insert adds a member (or element, when key is empty) at the end of the
+// container, copying the indentation and key separator of its last child.
+func (d *Document) insert(container *span, key string, text string) error {
+	member := text
+	if key != "" {
+		member = key + ": " + text
+	}
+	if len(container.children) == 0 {
+		return d.splice(container.start+1, container.start+1, member)
+	}
+
+	last := container.children[len(container.children)-1]
+	start := memberStart(last)
+	indent := start
+	for indent > 0 && strings.IndexByte(" \t\r\n", d.src[indent-1]) >= 0 {
+		indent--
+	}
+	sep := d.src[indent:start]
+	if sep == "" {
+		sep = " "
+	}
+	if key != "" {
+		member = key + d.src[last.keyEnd:last.start] + text
+	}
+
+	// A comment on the line of the last child stays with it, so the new
+	// member goes after the comment and the comma before it.
+	if next := d.skip(last.end); next < len(d.src) && d.src[next] == ',' {
+		end := d.lineComment(next + 1)
+		return d.splice(end, end, sep+member+",")
+	}
+	end := d.lineComment(last.end)
+	return d.splice(last.end, end, ","+d.src[last.end:end]+sep+member)
+}
+
+func memberStart(node *span) int {
+	if node.keyEnd > 0 {
+		return node.keyStart
+	}
+	return node.start
+}
+
+func (d *Document) skip(i int) int {
+	p := newParser(d.src, d.opts)
+	return p.skipWhitespace(i)
+}
+
+func (d *Document) splice(start, end int, text string) error {
+	return d.reset(d.src[:start] + text + d.src[end:])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
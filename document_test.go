@@ -0,0 +1,177 @@
+package jchain
+
+import (
+	"testing"
+)
+
+const documentSource = `{
+    // service settings
+    "name": "api",
+    "port": 8080, /* default */
+    "hosts": [
+        "a.example.com",
+        "b.example.com",
+    ],
+    "tls": {"enabled": false}
+}
+`
+
+func TestDocumentSet(t *testing.T) {
+	doc, err := ParseDocument(documentSource, ParseOptions{Relaxed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		ptr, val string
+	}{
+		{"/port", `9090`},
+		{"/tls/enabled", `true`},
+		{"/hosts/-", `"c.example.com"`},
+		{"/timeout", `{"read": 5}`},
+		{"/tls/cert", `"cert.pem"`},
+	}
+	for _, step := range steps {
+		if err := doc.Set(step.ptr, Parse(step.val)); err != nil {
+			t.Fatalf("Set(%s): %v", step.ptr, err)
+		}
+	}
+
+	want := `{
+    // service settings
+    "name": "api",
+    "port": 9090, /* default */
+    "hosts": [
+        "a.example.com",
+        "b.example.com",
+        "c.example.com",
+    ],
+    "tls": {"enabled": true, "cert": "cert.pem"},
+    "timeout": {"read":5}
+}
+`
+	if doc.String() != want {
+		t.Errorf("unexpected document:\n%s", doc.String())
+	}
+	if port, _ := doc.Value().Get("port").Int(); port != 9090 {
+		t.Errorf("expected port 9090, got %d", port)
+	}
+
+	// A comment after the last member stays on its line.
+	trailing := []struct{ src, ptr, want string }{
+		{"{\n    \"a\": 1 // about a\n}", "/b",
+			"{\n    \"a\": 1, // about a\n    \"b\": 2\n}"},
+		{"{\n    \"a\": 1, // about a\n}", "/b",
+			"{\n    \"a\": 1, // about a\n    \"b\": 2,\n}"},
+		{"[\n    1 /* one */\n]", "/-",
+			"[\n    1, /* one */\n    2\n]"},
+	}
+	for _, tt := range trailing {
+		doc, err := ParseDocument(tt.src, ParseOptions{Relaxed: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.Set(tt.ptr, Parse(`2`)); err != nil {
+			t.Fatalf("Set(%s): %v", tt.ptr, err)
+		}
+		if doc.String() != tt.want {
+			t.Errorf("Set(%s) after a commented member gave %q, want %q", tt.ptr, doc.String(), tt.want)
+		}
+	}
+}
+
+func TestDocumentDelete(t *testing.T) {
+	doc, err := ParseDocument(documentSource, ParseOptions{Relaxed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ptr := range []string{"/name", "/hosts/1", "/tls/enabled", "/tls"} {
+		if err := doc.Delete(ptr); err != nil {
+			t.Fatalf("Delete(%s): %v", ptr, err)
+		}
+	}
+
+	want := `{
+    // service settings
+    "port": 8080, /* default */
+    "hosts": [
+        "a.example.com",
+    ]
+}
+`
+	if doc.String() != want {
+		t.Errorf("unexpected document:\n%s", doc.String())
+	}
+
+	doc, err = ParseDocument(`{
+    "a": 1, // about a
+    // before b
+    "b": [1, 2, 3], // about b
+    // before c
+    "c": 3 // about c
+}`, ParseOptions{Relaxed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ptr := range []string{"/b/1", "/b/1", "/b", "/c"} {
+		if err := doc.Delete(ptr); err != nil {
+			t.Fatalf("Delete(%s): %v", ptr, err)
+		}
+	}
+	want = `{
+    "a": 1 // about a
+    // before b
+    // before c
+}`
+	if doc.String() != want {
+		t.Errorf("comments of neighbours were not kept:\n%s", doc.String())
+	}
+
+	lastMember := []struct{ src, ptr, want string }{
+		{"{\n    \"a\": {\n        \"x\": 1\n    },\n    \"b\": [\n        2\n    ]\n}", "/a/x",
+			"{\n    \"a\": {},\n    \"b\": [\n        2\n    ]\n}"},
+		{"{\n    \"a\": 1,\n    \"b\": [\n        2\n    ]\n}", "/b/0",
+			"{\n    \"a\": 1,\n    \"b\": []\n}"},
+		{"{\n    // keep\n    \"a\": 1\n}", "/a", "{\n    // keep\n}"},
+	}
+	for _, tt := range lastMember {
+		doc, err := ParseDocument(tt.src, ParseOptions{Relaxed: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.Delete(tt.ptr); err != nil {
+			t.Fatalf("Delete(%s): %v", tt.ptr, err)
+		}
+		if doc.String() != tt.want {
+			t.Errorf("Delete(%s) of the last member gave %q, want %q", tt.ptr, doc.String(), tt.want)
+		}
+	}
+
+	if err := doc.Delete("/missing"); err == nil {
+		t.Error("expected error deleting missing member")
+	}
+	if err := doc.Delete(""); err == nil {
+		t.Error("expected error deleting root")
+	}
+}
+
+func TestRelaxed(t *testing.T) {
+	if Parse(`[1, 2,]`).Error() == nil {
+		t.Error("expected trailing comma to be rejected in strict mode")
+	}
+	if Parse(`// comment
+		[1]`).Error() == nil {
+		t.Error("expected comment to be rejected in strict mode")
+	}
+	v := ParseWithOptions(`/* a */ {"a": [1, 2,], // b
+		"c": 3,}`, ParseOptions{Relaxed: true})
+	if err := v.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := v.Get("c").Int(); c != 3 {
+		t.Errorf("expected 3, got %d", c)
+	}
+	if ParseWithOptions(`[1] /* open`, ParseOptions{Relaxed: true}).Error() == nil {
+		t.Error("expected error for unterminated comment")
+	}
+}
@@ -0,0 +1,115 @@
+package jchain
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSON appends the compact JSON encoding of val to buf. Object keys are
+// sorted so the output is deterministic, and floats always carry a fraction
+// or exponent so they parse back as Float.
+func appendJSON(buf []byte, val any) ([]byte, error) {
+	switch val := val.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf = append(buf, '{')
+		for i, key := range keys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendString(buf, key)
+			buf = append(buf, ':')
+			var err error
+			buf, err = appendJSON(buf, val[key])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, '}'), nil
+	case []any:
+		buf = append(buf, '[')
+		for i, elem := range val {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			buf, err = appendJSON(buf, elem)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+	case string:
+		return appendString(buf, val), nil
+	case int64:
+		return strconv.AppendInt(buf, val, 10), nil
+	case uint64:
+		return strconv.AppendUint(buf, val, 10), nil
+	case *big.Int:
+		return val.Append(buf, 10), nil
+	case float64:
+		return appendFloat(buf, val)
+	case bool:
+		return strconv.AppendBool(buf, val), nil
+	case nil:
+		return append(buf, "null"...), nil
+	default:
+		return nil, fmt.Errorf("invalid value %T", val)
+	}
+}
+
+func appendFloat(buf []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("unsupported float %v", f)
+	}
+	start := len(buf)
+	buf = strconv.AppendFloat(buf, f, 'g', -1, 64)
+	if !strings.ContainsAny(string(buf[start:]), ".e") {
+		buf = append(buf, ".0"...)
+	}
+	return buf, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, "\ufffd"...)
+		} else {
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,246 @@
+package jchain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+)
+
+type NumberMode int
+
+const (
+	// ExactNumbers treats Int and Float values as different even when they
+	// hold the same number. Int values compare by value regardless of
+	// whether they are stored as int64, uint64 or *big.Int.
+	ExactNumbers NumberMode = iota
+	// NumericNumbers compares numbers by value, so 1 equals 1.0.
+	NumericNumbers
+)
+
+func (v *Value) Equal(other *Value) bool {
+	return v.EqualWith(other, ExactNumbers)
+}
+
+func (v *Value) EqualWith(other *Value, mode NumberMode) bool {
+	if v.err != nil || other.err != nil {
+		return false
+	}
+	return equalWith(v.data, other.data, mode)
+}
+
+func equalData(a, b any) bool {
+	return equalWith(a, b, ExactNumbers)
+}
+
+func equalWith(a, b any, mode NumberMode) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, val := range a {
+			other, ok := b[key]
+			if !ok || !equalWith(val, other, mode) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalWith(a[i], b[i], mode) {
+				return false
+			}
+		}
+		return true
+	case int64, uint64, float64, *big.Int:
+		return equalNumbers(a, b, mode)
+	default:
+		return a == b
+	}
+}
+
+func equalNumbers(a, b any, mode NumberMode) bool {
+	if f, ok := a.(float64); ok {
+		if g, ok := b.(float64); ok {
+			return f == g
+		}
+		if mode != NumericNumbers {
+			return false
+		}
+		a, b = b, f
+	}
+	if n, ok := a.(*big.Int); ok {
+		return equalBig(n, b, mode)
+	}
+	if n, ok := b.(*big.Int); ok {
+		return equalBig(n, a, mode)
+	}
+
+	switch a := a.(type) {
+	case int64:
+		switch b := b.(type) {
+		case int64:
+			return a == b
+		case uint64:
+			return a >= 0 && uint64(a) == b
+		case float64:
+			return mode == NumericNumbers && b == math.Trunc(b) && b >= -(1<<63) && b < 1<<63 && int64(b) == a
+		}
+	case uint64:
+		switch b := b.(type) {
+		case uint64:
+			return a == b
+		case int64:
+			return b >= 0 && uint64(b) == a
+		case float64:
+			return mode == NumericNumbers && b == math.Trunc(b) && b >= 0 && b < 1<<64 && uint64(b) == a
+		}
+	}
+	return false
+}
+
+// equalBig compares a *big.Int with any number. A float only matches in
+// NumericNumbers mode, when it is an integer of the same value.
+func equalBig(n *big.Int, other any, mode NumberMode) bool {
+	if f, ok := other.(float64); ok {
+		if mode != NumericNumbers || math.IsInf(f, 0) || f != math.Trunc(f) {
+			return false
+		}
+		m, _ := big.NewFloat(f).Int(nil)
+		return n.Cmp(m) == 0
+	}
+	m, ok := toBig(other)
+	return ok && n.Cmp(m) == 0
+}
+
+// Hash returns a stable 64-bit hash of the value. Values that are Equal have
+// the same hash; object key order does not matter.
+func (v *Value) Hash() (uint64, error) {
+	return v.HashWith(ExactNumbers)
+}
+
+// HashWith is like Hash but agrees with EqualWith for the given mode.
+func (v *Value) HashWith(mode NumberMode) (uint64, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	h := &hasher{mode: mode, h: fnv.New64a()}
+	if err := h.write(v.data); err != nil {
+		return 0, err
+	}
+	return h.h.Sum64(), nil
+}
+
+type hasher struct {
+	mode    NumberMode
+	h       hash.Hash64
+	scratch [8]byte
+}
+
+func (h *hasher) tag(k Kind, sign byte) {
+	h.scratch[0] = byte(k)
+	h.scratch[1] = sign
+	h.h.Write(h.scratch[:2])
+}
+
+func (h *hasher) uint(n uint64) {
+	binary.BigEndian.PutUint64(h.scratch[:8], n)
+	h.h.Write(h.scratch[:8])
+}
+
+func (h *hasher) str(s string) {
+	h.uint(uint64(len(s)))
+	io.WriteString(h.h, s)
+}
+
+func (h *hasher) write(val any) error {
+	switch val := val.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		h.tag(Object, 0)
+		h.uint(uint64(len(keys)))
+		for _, key := range keys {
+			h.str(key)
+			if err := h.write(val[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		h.tag(Array, 0)
+		h.uint(uint64(len(val)))
+		for _, elem := range val {
+			if err := h.write(elem); err != nil {
+				return err
+			}
+		}
+	case string:
+		h.tag(String, 0)
+		h.str(val)
+	case int64:
+		if val >= 0 {
+			h.writeUint(uint64(val))
+		} else {
+			h.tag(Int, '-')
+			h.uint(uint64(val))
+		}
+	case uint64:
+		h.writeUint(val)
+	case *big.Int:
+		norm := normalizeBig(val)
+		n, ok := norm.(*big.Int)
+		if !ok {
+			return h.write(norm)
+		}
+		// A float holding the same integer hashes as that float.
+		if f, exact := bigFloat(n); h.mode == NumericNumbers && exact {
+			return h.write(f)
+		}
+		h.tag(Int, 'b')
+		h.str(n.String())
+	case float64:
+		if h.mode == NumericNumbers && val == math.Trunc(val) {
+			if val >= 0 && val < 1<<64 {
+				h.writeUint(uint64(val))
+				return nil
+			}
+			if val < 0 && val >= -(1<<63) {
+				return h.write(int64(val))
+			}
+		}
+		if val == 0 {
+			val = 0 // fold -0 into 0, they compare equal
+		}
+		h.tag(Float, 0)
+		h.uint(math.Float64bits(val))
+	case bool:
+		if val {
+			h.tag(Bool, 1)
+		} else {
+			h.tag(Bool, 0)
+		}
+	case nil:
+		h.tag(Null, 0)
+	default:
+		return fmt.Errorf("invalid value %T", val)
+	}
+	return nil
+}
+
+func (h *hasher) writeUint(n uint64) {
+	h.tag(Int, '+')
+	h.uint(n)
+}
@@ -0,0 +1,77 @@
+package jchain
+
+import (
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	a := Parse(`{"a": [1, 2.5, "x"], "b": {"c": null, "d": true}}`)
+	b := Parse(`{"b": {"d": true, "c": null}, "a": [1, 2.5, "x"]}`)
+	if !a.Equal(b) {
+		t.Error("expected documents to be equal")
+	}
+	if a.Equal(Parse(`{"a": [1, 2.5, "y"], "b": {"c": null, "d": true}}`)) {
+		t.Error("expected documents to differ")
+	}
+
+	signed := &Value{kind: Int, data: int64(1)}
+	unsigned := &Value{kind: Int, data: uint64(1)}
+	float := &Value{kind: Float, data: float64(1)}
+	if !signed.Equal(unsigned) {
+		t.Error("expected int64(1) to equal uint64(1)")
+	}
+	if signed.Equal(float) {
+		t.Error("expected int64(1) and float64(1) to differ with exact numbers")
+	}
+	if !signed.EqualWith(float, NumericNumbers) || !float.EqualWith(unsigned, NumericNumbers) {
+		t.Error("expected 1 and 1.0 to be equal with numeric numbers")
+	}
+	if (&Value{kind: Float, data: 1.5}).EqualWith(signed, NumericNumbers) {
+		t.Error("expected 1.5 and 1 to differ")
+	}
+	if Parse(`{`).Equal(Parse(`{`)) {
+		t.Error("expected invalid values to never be equal")
+	}
+}
+
+func TestHash(t *testing.T) {
+	hash := func(v *Value, mode NumberMode) uint64 {
+		h, err := v.HashWith(mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	a := Parse(`{"a": [1, 2.5, "x"], "b": {"c": null, "d": true}}`)
+	b := Parse(`{"b": {"d": true, "c": null}, "a": [1, 2.5, "x"]}`)
+	if hash(a, ExactNumbers) != hash(b, ExactNumbers) {
+		t.Error("expected equal documents to hash the same")
+	}
+	if hash(a, ExactNumbers) == hash(Parse(`{"a": [1, 2.5, "x"], "b": {"c": null, "d": false}}`), ExactNumbers) {
+		t.Error("expected different documents to hash differently")
+	}
+	if hash(Parse(`["ab", "c"]`), ExactNumbers) == hash(Parse(`["a", "bc"]`), ExactNumbers) {
+		t.Error("expected string boundaries to affect the hash")
+	}
+
+	signed := &Value{kind: Int, data: int64(7)}
+	unsigned := &Value{kind: Int, data: uint64(7)}
+	float := &Value{kind: Float, data: float64(7)}
+	if hash(signed, ExactNumbers) != hash(unsigned, ExactNumbers) {
+		t.Error("expected int64 and uint64 to hash the same")
+	}
+	if hash(signed, ExactNumbers) == hash(float, ExactNumbers) {
+		t.Error("expected int and float to hash differently with exact numbers")
+	}
+	if hash(signed, NumericNumbers) != hash(float, NumericNumbers) {
+		t.Error("expected int and float to hash the same with numeric numbers")
+	}
+	if hash(Parse(`-0.0`), ExactNumbers) != hash(Parse(`0.0`), ExactNumbers) {
+		t.Error("expected -0 and 0 to hash the same")
+	}
+
+	if _, err := Parse(`[`).Hash(); err == nil {
+		t.Error("expected error hashing invalid value")
+	}
+}
@@ -0,0 +1,148 @@
+package jchain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// snippetWidth is how many bytes of the offending line are shown on each
+// side of the error column when the line is too long to print in full.
+const snippetWidth = 40
+
+// SyntaxError describes malformed input. Its message includes the offending
+// line with a caret under the error column.
+type SyntaxError struct {
+	Msg    string
+	Found  string
+	Offset int
+	Line   int
+	Column int
+	// Opened is where the object, array or string left unclosed at the end
+	// of the input started, and OpenedKind what it was.
+	Opened     *Position
+	OpenedKind Kind
+	// Snippet is the offending line and Caret the line marking the column.
+	Snippet string
+	Caret   string
+}
+
+func (e *SyntaxError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Msg)
+	if e.Found != "" {
+		sb.WriteString(", found ")
+		sb.WriteString(e.Found)
+	}
+	fmt.Fprintf(&sb, " at line %d, column %d", e.Line, e.Column)
+	if e.Opened != nil {
+		fmt.Fprintf(&sb, " (%s opened at line %d, column %d)", e.OpenedKind, e.Opened.Line, e.Opened.Column)
+	}
+	if e.Snippet != "" || e.Caret != "" {
+		sb.WriteString("\n")
+		sb.WriteString(e.Snippet)
+		sb.WriteString("\n")
+		sb.WriteString(e.Caret)
+	}
+	return sb.String()
+}
+
+func newSyntaxError(src *source, input string, pErr parserError) *SyntaxError {
+	line, col := src.lineCol(pErr.pos)
+	e := &SyntaxError{
+		Msg:    pErr.msg,
+		Found:  describeFound(input, pErr.pos),
+		Offset: pErr.pos,
+		Line:   line,
+		Column: col,
+	}
+	if pErr.opened >= 0 && pErr.pos >= len(input) {
+		openLine, openCol := src.lineCol(pErr.opened)
+		e.Opened = &Position{Offset: pErr.opened, Line: openLine, Column: openCol}
+		switch input[pErr.opened] {
+		case '{':
+			e.OpenedKind = Object
+		case '[':
+			e.OpenedKind = Array
+		case '"':
+			e.OpenedKind = String
+		}
+	}
+	e.Snippet, e.Caret = snippet(src, input, line, col)
+	return e
+}
+
+func describeFound(input string, pos int) string {
+	if pos >= len(input) {
+		return "end of input"
+	}
+	r, size := utf8.DecodeRuneInString(input[pos:])
+	if r == utf8.RuneError && size == 1 {
+		return fmt.Sprintf("byte %#02x", input[pos])
+	}
+	if unicode.IsLetter(r) {
+		end := pos
+		for end < len(input) && end-pos < 20 {
+			r, size := utf8.DecodeRuneInString(input[end:])
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+				break
+			}
+			end += size
+		}
+		return "'" + input[pos:end] + "'"
+	}
+	if r < 0x20 {
+		return fmt.Sprintf("%q", r)
+	}
+	return "'" + string(r) + "'"
+}
+
+func snippet(src *source, input string, line, col int) (string, string) {
+	start := src.lines[line-1]
+	end := len(input)
+	if line < len(src.lines) {
+		end = src.lines[line] - 1
+	}
+	text := strings.TrimRight(input[start:end], "\r")
+	pos := col - 1
+
+	prefix, suffix := "", ""
+	if len(text) > 2*snippetWidth {
+		from, to := pos-snippetWidth, pos+snippetWidth
+		if from < 0 {
+			from = 0
+		}
+		if to > len(text) {
+			to = len(text)
+		}
+		for from > 0 && !utf8.RuneStart(text[from]) {
+			from--
+		}
+		for to < len(text) && !utf8.RuneStart(text[to]) {
+			to++
+		}
+		if from > 0 {
+			prefix = "..."
+		}
+		if to < len(text) {
+			suffix = "..."
+		}
+		text, pos = text[from:to], pos-from
+	}
+	if pos > len(text) {
+		pos = len(text)
+	}
+
+	var caret strings.Builder
+	caret.WriteString(strings.Repeat(" ", len(prefix)))
+	for _, r := range text[:pos] {
+		if r == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	caret.WriteByte('^')
+	return prefix + text + suffix, caret.String()
+}
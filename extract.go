@@ -0,0 +1,248 @@
+package jchain
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pathToken is one step of an extraction path. index is the token read as an
+// array index, or -1 when it is not one.
+type pathToken struct {
+	name  string
+	index int
+	any   bool
+}
+
+type extractFrame struct {
+	array bool
+	key   string
+	index int
+}
+
+// Extract reads a JSON document from r and calls fn for every value matching
+// one of paths. A path is either a JSON Pointer ("/records/*/id") or a simple
+// path ("$.records[*].id"); "*" matches any object member or array element.
+// Subtrees that cannot match are skipped without being decoded, so memory
+// use depends on the size of the matches rather than of the document. A match
+// nested inside another match is only reported as part of the outer one.
+//
+// fn receives the JSON Pointer of the match. Returning an error from fn stops
+// the extraction and Extract returns that error.
+func Extract(r io.Reader, paths []string, fn func(ptr string, v *Value) error) error {
+	patterns := make([][]pathToken, len(paths))
+	for i, path := range paths {
+		pattern, err := compilePath(path)
+		if err != nil {
+			return err
+		}
+		patterns[i] = pattern
+	}
+
+	tok := NewTokenizer(r)
+	var frames []extractFrame
+	for {
+		ev, err := tok.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch ev.Type {
+		case EventKey:
+			frames[len(frames)-1].key = ev.Value
+			continue
+		case EventEnd:
+			frames = frames[:len(frames)-1]
+			continue
+		}
+		if len(frames) > 0 && frames[len(frames)-1].array {
+			frames[len(frames)-1].index++
+		}
+
+		full, prefix := matchFrames(patterns, frames)
+		container := ev.Type == EventBeginObject || ev.Type == EventBeginArray
+		switch {
+		case full:
+			data, err := tok.decode(ev)
+			if err != nil {
+				return err
+			}
+			if err := fn(extractPointer(frames), &Value{kind: getKind(data), data: data}); err != nil {
+				return err
+			}
+		case prefix && container:
+			frames = append(frames, extractFrame{array: ev.Type == EventBeginArray, index: -1})
+		case container:
+			if err := tok.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// compilePath splits a JSON Pointer or a simple "$." path into tokens.
+func compilePath(path string) ([]pathToken, error) {
+	var names []string
+	if path == "" || path[0] == '/' {
+		tokens, err := parsePointer(path)
+		if err != nil {
+			return nil, err
+		}
+		names = tokens
+	} else {
+		tokens, err := parseSimplePath(path)
+		if err != nil {
+			return nil, err
+		}
+		names = tokens
+	}
+
+	pattern := make([]pathToken, len(names))
+	for i, name := range names {
+		pattern[i] = pathToken{name: name, index: -1, any: name == "*"}
+		if idx, err := arrayIndex(name, int(^uint(0)>>1)); err == nil {
+			pattern[i].index = idx
+		}
+	}
+	return pattern, nil
+}
+
+// parseSimplePath reads paths of the form $.a.b[0]['c d'][*].
+func parseSimplePath(path string) ([]string, error) {
+	if path[0] != '$' {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	var tokens []string
+	for i := 1; i < len(path); {
+		switch path[i] {
+		case '.':
+			end := i + 1
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			if end == i+1 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			tokens = append(tokens, path[i+1:end])
+			i = end
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			inner := path[i+1 : i+end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				inner = inner[1 : len(inner)-1]
+			} else if inner != "*" {
+				if _, err := strconv.Atoi(inner); err != nil {
+					return nil, fmt.Errorf("invalid path %q", path)
+				}
+			}
+			tokens = append(tokens, inner)
+			i += end + 1
+		default:
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+	}
+	return tokens, nil
+}
+
+// matchFrames reports whether frames, the path of the current value, matches a
+// pattern in full, and whether it matches the start of a longer one.
+func matchFrames(patterns [][]pathToken, frames []extractFrame) (full, prefix bool) {
+	for _, pattern := range patterns {
+		if len(pattern) < len(frames) {
+			continue
+		}
+		ok := true
+		for i, frame := range frames {
+			t := pattern[i]
+			if t.any {
+				continue
+			}
+			if (frame.array && t.index != frame.index) || (!frame.array && t.name != frame.key) {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		if len(pattern) == len(frames) {
+			return true, false
+		}
+		prefix = true
+	}
+	return false, prefix
+}
+
+func extractPointer(frames []extractFrame) string {
+	tokens := make([]string, len(frames))
+	for i, frame := range frames {
+		if frame.array {
+			tokens[i] = strconv.Itoa(frame.index)
+		} else {
+			tokens[i] = frame.key
+		}
+	}
+	return formatPointer(tokens)
+}
+
+// decode builds the value that starts with ev.
+func (t *Tokenizer) decode(ev Event) (any, error) {
+	switch ev.Type {
+	case EventString:
+		return ev.Value, nil
+	case EventNumber:
+		return numberValue(ev.Value, strings.IndexAny(ev.Value, ".eE") < 0, false)
+	case EventBool:
+		return ev.Bool, nil
+	case EventNull:
+		return nil, nil
+	case EventBeginObject:
+		obj := map[string]any{}
+		for {
+			key, err := t.Next()
+			if err != nil {
+				return nil, err
+			}
+			if key.Type == EventEnd {
+				return obj, nil
+			}
+			if _, ok := obj[key.Value]; ok {
+				return nil, &SyntaxError{Msg: "duplicate key " + key.Value, Offset: int(key.Offset), Line: t.tokLine, Column: t.tokCol}
+			}
+			next, err := t.Next()
+			if err != nil {
+				return nil, err
+			}
+			val, err := t.decode(next)
+			if err != nil {
+				return nil, err
+			}
+			obj[key.Value] = val
+		}
+	case EventBeginArray:
+		arr := []any{}
+		for {
+			next, err := t.Next()
+			if err != nil {
+				return nil, err
+			}
+			if next.Type == EventEnd {
+				return arr, nil
+			}
+			val, err := t.decode(next)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected %s", ev.Type)
+	}
+}
@@ -0,0 +1,79 @@
+package jchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	input := `{
+		"meta": {"count": 3, "tags": ["a", {"id": "not me"}]},
+		"records": [
+			{"id": 1, "body": {"id": "nested"}},
+			{"id": "two", "body": [1, 2, 3]},
+			{"body": null},
+			{"id": {"deep": [true]}}
+		],
+		"extra": "xé"
+	}`
+
+	tests := []struct {
+		paths []string
+		want  []string
+	}{
+		{[]string{"$.records[*].id"}, []string{`/records/0/id=1`, `/records/1/id="two"`, `/records/3/id={"deep":[true]}`}},
+		{[]string{"/records/*/id"}, []string{`/records/0/id=1`, `/records/1/id="two"`, `/records/3/id={"deep":[true]}`}},
+		{[]string{"$.records[1].body", "/extra"}, []string{`/records/1/body=[1,2,3]`, `/extra="xé"`}},
+		{[]string{"$['meta'].count", "/meta/tags/1"}, []string{`/meta/count=3`, `/meta/tags/1={"id":"not me"}`}},
+		{[]string{"/missing/*"}, nil},
+		{[]string{""}, []string{`={"extra":"xé","meta":{"count":3,"tags":["a",{"id":"not me"}]},"records":[{"body":{"id":"nested"},"id":1},{"body":[1,2,3],"id":"two"},{"body":null},{"id":{"deep":[true]}}]}`}},
+	}
+	for _, tt := range tests {
+		var got []string
+		err := Extract(strings.NewReader(input), tt.paths, func(ptr string, v *Value) error {
+			text, err := appendJSON(nil, v.data)
+			if err != nil {
+				return err
+			}
+			got = append(got, ptr+"="+string(text))
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%v: %v", tt.paths, err)
+			continue
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%v: got\n%s\nwant\n%s", tt.paths, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	stop := errors.New("stop")
+	calls := 0
+	err := Extract(strings.NewReader(`[1, 2, 3]`), []string{"/*"}, func(string, *Value) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("expected callback error after one call, got %v after %d", err, calls)
+	}
+
+	var se *SyntaxError
+	err = Extract(strings.NewReader(`{"skip": [1, }, "id": 1}`), []string{"/id"}, func(string, *Value) error { return nil })
+	if !errors.As(err, &se) {
+		t.Errorf("expected syntax error in skipped subtree, got %v", err)
+	}
+
+	err = Extract(strings.NewReader(`{"a": {"b": 1, "b": 2}}`), []string{"/a"}, func(string, *Value) error { return nil })
+	if !errors.As(err, &se) || se.Msg != "duplicate key b" || se.Column != 16 {
+		t.Errorf("expected duplicate key error at column 16, got %v", err)
+	}
+
+	for _, path := range []string{"records", "$.", "$[x]", "/a~2"} {
+		if err := Extract(strings.NewReader(`{}`), []string{path}, nil); err == nil {
+			t.Errorf("expected %q to be rejected", path)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package jchain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// IndexStyle selects how Flatten writes array indexes.
+type IndexStyle int
+
+const (
+	// DotIndex writes indexes as keys, as in a.b.0.
+	DotIndex IndexStyle = iota
+	// BracketIndex writes indexes in brackets, as in a.b[0].
+	BracketIndex
+)
+
+type FlattenOptions struct {
+	// Separator joins keys. Empty means ".".
+	Separator string
+	Index     IndexStyle
+	// MaxDepth limits how many keys a flattened key joins; values below it
+	// are kept whole. Zero means no limit.
+	MaxDepth int
+}
+
+func (o FlattenOptions) separator() string {
+	if o.Separator == "" {
+		return "."
+	}
+	return o.Separator
+}
+
+// escapeKey puts a backslash before backslashes, before every rune of key
+// that also occurs in the separator and, with BracketIndex, before '['.
+// Escaping single runes rather than whole separators keeps keys such as "a_"
+// from running into a separator "__" that follows them.
+func (o FlattenOptions) escapeKey(key string) string {
+	sep := o.separator()
+	var sb strings.Builder
+	for _, r := range key {
+		if r == '\\' || strings.ContainsRune(sep, r) || r == '[' && o.Index == BracketIndex {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// joinKey appends key to the flattened key prefix; root is set for the
+// first key.
+func (o FlattenOptions) joinKey(prefix string, key string, root bool) string {
+	if root {
+		return o.escapeKey(key)
+	}
+	return prefix + o.separator() + o.escapeKey(key)
+}
+
+func (o FlattenOptions) joinIndex(prefix string, i int, root bool) string {
+	if o.Index == BracketIndex {
+		return prefix + "[" + strconv.Itoa(i) + "]"
+	}
+	return o.joinKey(prefix, strconv.Itoa(i), root)
+}
+
+// flatPart is one step of a flattened key: an object key, or an array index
+// written in brackets.
+type flatPart struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// splitKey undoes joinKey and joinIndex.
+func (o FlattenOptions) splitKey(flat string) ([]flatPart, error) {
+	sep := o.separator()
+	var parts []flatPart
+	var cur strings.Builder
+	pending := true
+	for i := 0; i < len(flat); {
+		switch {
+		case flat[i] == '\\' && i+1 < len(flat):
+			_, size := utf8.DecodeRuneInString(flat[i+1:])
+			cur.WriteString(flat[i+1 : i+1+size])
+			i += 1 + size
+		case strings.HasPrefix(flat[i:], sep):
+			if pending {
+				parts = append(parts, flatPart{key: cur.String()})
+			}
+			cur.Reset()
+			pending = true
+			i += len(sep)
+		case flat[i] == '[' && o.Index == BracketIndex:
+			if pending && (cur.Len() > 0 || len(parts) > 0) {
+				parts = append(parts, flatPart{key: cur.String()})
+			}
+			cur.Reset()
+			end := strings.IndexByte(flat[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid index in key %s", flat)
+			}
+			n, err := strconv.Atoi(flat[i+1 : i+end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid index in key %s", flat)
+			}
+			parts = append(parts, flatPart{index: n, isIndex: true})
+			pending = false
+			i += end + 1
+		default:
+			if !pending {
+				return nil, fmt.Errorf("invalid index in key %s", flat)
+			}
+			cur.WriteByte(flat[i])
+			i++
+		}
+	}
+	if pending {
+		parts = append(parts, flatPart{key: cur.String()})
+	}
+	return parts, nil
+}
+
+// Flatten turns an object or array into a single-level object whose keys
+// join the keys and indexes leading to each value, so {"a": {"b": [1, 2]}}
+// becomes {"a.b.0": 1, "a.b.1": 2}. Characters of keys that occur in the
+// separator are escaped with backslashes, and empty objects and arrays are
+// kept as values.
+func Flatten(v *Value, opts FlattenOptions) *Value {
+	if v.err != nil {
+		return &Value{err: v.err}
+	}
+	switch v.data.(type) {
+	case map[string]any, []any:
+	default:
+		return &Value{err: fmt.Errorf("not object or array")}
+	}
+	out := map[string]any{}
+	opts.flatten(out, "", v.data, 0)
+	return &Value{kind: Object, data: out}
+}
+
+func (o FlattenOptions) flatten(out map[string]any, prefix string, val any, depth int) {
+	if depth > 0 && o.MaxDepth > 0 && depth >= o.MaxDepth {
+		out[prefix] = cloneData(val)
+		return
+	}
+	switch val := val.(type) {
+	case map[string]any:
+		if len(val) > 0 || depth == 0 {
+			for k, child := range val {
+				o.flatten(out, o.joinKey(prefix, k, depth == 0), child, depth+1)
+			}
+			return
+		}
+		out[prefix] = map[string]any{}
+		return
+	case []any:
+		if len(val) > 0 || depth == 0 {
+			for i, child := range val {
+				o.flatten(out, o.joinIndex(prefix, i, depth == 0), child, depth+1)
+			}
+			return
+		}
+		out[prefix] = []any{}
+		return
+	}
+	out[prefix] = val
+}
+
+// Unflatten reverses Flatten. Bracketed indexes always make arrays, with
+// null for indexes that are not given, but each index must be below the
+// number of keys; with DotIndex, objects whose keys are exactly 0 to n-1
+// become arrays.
+func Unflatten(v *Value, opts FlattenOptions) *Value {
+	if v.err != nil {
+		return &Value{err: v.err}
+	}
+	flat, ok := v.data.(map[string]any)
+	if !ok {
+		return &Value{err: fmt.Errorf("not object")}
+	}
+	res, err := opts.unflatten(flat)
+	if err != nil {
+		return &Value{err: err}
+	}
+	return &Value{kind: getKind(res), data: res}
+}
+
+// flatArray collects the items of an array by index while unflattening.
+type flatArray map[int]any
+
+func (o FlattenOptions) unflatten(flat map[string]any) (any, error) {
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var root any = map[string]any{}
+	if len(keys) > 0 {
+		root = nil
+	}
+	for _, k := range keys {
+		parts, err := o.splitKey(k)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parts {
+			if p.isIndex && p.index >= len(flat) {
+				return nil, fmt.Errorf("index %d out of range in key %s", p.index, k)
+			}
+		}
+		var ok bool
+		if root, ok = insertFlat(root, parts, cloneData(flat[k])); !ok {
+			return nil, fmt.Errorf("key %s conflicts with another key", k)
+		}
+	}
+	return o.finishFlat(root), nil
+}
+
+// insertFlat stores val at parts below node, reporting false when a value
+// is already there or in the way.
+func insertFlat(node any, parts []flatPart, val any) (any, bool) {
+	if len(parts) == 0 {
+		if node == nil {
+			return val, true
+		}
+		// An empty object or array kept by Flatten may meet the keys below
+		// it.
+		switch val := val.(type) {
+		case map[string]any:
+			if _, ok := node.(map[string]any); ok && len(val) == 0 {
+				return node, true
+			}
+		case []any:
+			if _, ok := node.(flatArray); ok && len(val) == 0 {
+				return node, true
+			}
+		}
+		return node, false
+	}
+
+	p := parts[0]
+	if p.isIndex {
+		arr, ok := node.(flatArray)
+		if node == nil {
+			arr = flatArray{}
+		} else if list, isList := node.([]any); isList && len(list) == 0 {
+			arr = flatArray{}
+		} else if !ok {
+			return node, false
+		}
+		child, exists := arr[p.index]
+		if exists && child == nil {
+			return node, false
+		}
+		child, ok = insertFlat(child, parts[1:], val)
+		arr[p.index] = child
+		return arr, ok
+	}
+	obj, ok := node.(map[string]any)
+	if node == nil {
+		obj = map[string]any{}
+	} else if !ok {
+		return node, false
+	}
+	child, exists := obj[p.key]
+	if exists && child == nil {
+		return node, false
+	}
+	child, ok = insertFlat(child, parts[1:], val)
+	obj[p.key] = child
+	return obj, ok
+}
+
+func (o FlattenOptions) finishFlat(node any) any {
+	switch node := node.(type) {
+	case flatArray:
+		n := 0
+		for i := range node {
+			if i >= n {
+				n = i + 1
+			}
+		}
+		arr := make([]any, n)
+		for i, v := range node {
+			arr[i] = o.finishFlat(v)
+		}
+		return arr
+	case map[string]any:
+		for k, v := range node {
+			node[k] = o.finishFlat(v)
+		}
+		if o.Index == DotIndex {
+			return indexedToArray(node)
+		}
+	}
+	return node
+}
+
+// indexedToArray turns an object whose keys are exactly 0 to n-1 into an
+// array.
+func indexedToArray(obj map[string]any) any {
+	if len(obj) == 0 {
+		return obj
+	}
+	arr := make([]any, len(obj))
+	for k, v := range obj {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(arr) || strconv.Itoa(i) != k {
+			return obj
+		}
+		arr[i] = v
+	}
+	return arr
+}
@@ -0,0 +1,91 @@
+package jchain
+
+import "testing"
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts FlattenOptions
+		want string
+	}{
+		{"dot", `{"a": {"b": [1, 2]}, "c": null}`, FlattenOptions{},
+			`{"a.b.0": 1, "a.b.1": 2, "c": null}`},
+		{"bracket", `{"a": {"b": [1, {"c": true}]}}`, FlattenOptions{Index: BracketIndex},
+			`{"a.b[0]": 1, "a.b[1].c": true}`},
+		{"separator", `{"a": {"b": [1]}}`, FlattenOptions{Separator: "__"},
+			`{"a__b__0": 1}`},
+		{"root array", `[{"a": 1}, [2]]`, FlattenOptions{Index: BracketIndex},
+			`{"[0].a": 1, "[1][0]": 2}`},
+		{"empty", `{"a": {}, "b": [], "c": {"d": {}}}`, FlattenOptions{},
+			`{"a": {}, "b": [], "c.d": {}}`},
+		{"escaped", `{"a.b": {"c\\d": 1, "e[0]": 2}}`, FlattenOptions{Index: BracketIndex},
+			`{"a\\.b.c\\\\d": 1, "a\\.b.e\\[0]": 2}`},
+		{"max depth", `{"a": {"b": {"c": [1]}}, "d": 2}`, FlattenOptions{MaxDepth: 2},
+			`{"a.b": {"c": [1]}, "d": 2}`},
+		{"empty root", `{}`, FlattenOptions{}, `{}`},
+		{"separator in keys", `{"a_": {"b": 1}, "c": {"_d": 2}, "e__f": 3}`, FlattenOptions{Separator: "__"},
+			`{"a\\___b": 1, "c__\\_d": 2, "e\\_\\_f": 3}`},
+		{"multi-byte separator", `{"x:": {":y": [1]}, "→": {"a→b": 2}}`, FlattenOptions{Separator: "::", Index: BracketIndex},
+			`{"x\\:::\\:y[0]": 1, "→::a→b": 2}`},
+		{"unicode separator", `{"a": {"b→": 1}, "é": 2}`, FlattenOptions{Separator: "→"},
+			`{"a→b\\→": 1, "é": 2}`},
+	}
+	for _, tt := range tests {
+		got := Flatten(Parse(tt.in), tt.opts)
+		if !got.Equal(Parse(tt.want)) {
+			t.Errorf("%s: got %v, %v, want %s", tt.name, got, got.Error(), tt.want)
+			continue
+		}
+		if back := Unflatten(got, tt.opts); !back.Equal(Parse(tt.in)) {
+			t.Errorf("%s: round trip gave %v, %v", tt.name, back, back.Error())
+		}
+	}
+
+	if err := Flatten(Parse(`1`), FlattenOptions{}).Error(); err == nil || err.Error() != "not object or array" {
+		t.Errorf("expected not object or array, got %v", err)
+	}
+	if err := Flatten(Parse(`{`), FlattenOptions{}).Error(); err == nil {
+		t.Error("expected the chain error to be returned")
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts FlattenOptions
+		want string
+	}{
+		{"dot sparse", `{"a.0": 1, "a.2": 2}`, FlattenOptions{}, `{"a": {"0": 1, "2": 2}}`},
+		{"bracket sparse", `{"a[0]": 1, "a[2]": 2, "b": 3}`, FlattenOptions{Index: BracketIndex}, `{"a": [1, null, 2], "b": 3}`},
+		{"bracket keeps numeric keys", `{"a.0": 1}`, FlattenOptions{Index: BracketIndex}, `{"a": {"0": 1}}`},
+		{"empty meets keys", `{"a": {}, "a.b": 1}`, FlattenOptions{}, `{"a": {"b": 1}}`},
+		{"leaf object", `{"a": {"b": 1}}`, FlattenOptions{}, `{"a": {"b": 1}}`},
+	}
+	for _, tt := range tests {
+		got := Unflatten(Parse(tt.in), tt.opts)
+		if !got.Equal(Parse(tt.want)) {
+			t.Errorf("%s: got %v, %v, want %s", tt.name, got, got.Error(), tt.want)
+		}
+	}
+
+	errs := []struct {
+		in   string
+		opts FlattenOptions
+		msg  string
+	}{
+		{`{"a": 1, "a.b": 2}`, FlattenOptions{}, "key a.b conflicts with another key"},
+		{`{"a": null, "a.b": 2}`, FlattenOptions{}, "key a.b conflicts with another key"},
+		{`{"a": [1], "a.0": 2}`, FlattenOptions{}, "key a.0 conflicts with another key"},
+		{`{"a[x]": 1}`, FlattenOptions{Index: BracketIndex}, "invalid index in key a[x]"},
+		{`{"a[0]b": 1}`, FlattenOptions{Index: BracketIndex}, "invalid index in key a[0]b"},
+		{`{"a[1000000]": 1}`, FlattenOptions{Index: BracketIndex}, "index 1000000 out of range in key a[1000000]"},
+		{`[1]`, FlattenOptions{}, "not object"},
+	}
+	for _, tt := range errs {
+		if err := Unflatten(Parse(tt.in), tt.opts).Error(); err == nil || err.Error() != tt.msg {
+			t.Errorf("%s: expected %q, got %v", tt.in, tt.msg, err)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package jchain
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fromMaxDepth bounds the nesting From follows, which also stops it on
+// pointer cycles.
+const fromMaxDepth = 1000
+
+// From converts Go data into a Value, following the rules of encoding/json:
+// struct fields are named by their json tags and honour "-", omitempty and
+// the string option, embedded structs are flattened, []byte becomes a base64
+// string, nil pointers, slices and maps become null, values implementing
+// json.Marshaler (such as json.RawMessage) are parsed, and values implementing
+// encoding.TextMarshaler become strings. Numbers, including json.Number, are
+// normalized the way the parser normalizes them; a *big.Int stays exact.
+func From(val any) *Value {
+	data, err := fromReflect(reflect.ValueOf(val), 0)
+	if err != nil {
+		return &Value{err: err}
+	}
+	return &Value{kind: getKind(data), data: data}
+}
+
+func fromReflect(rv reflect.Value, depth int) (any, error) {
+	if depth > fromMaxDepth {
+		return nil, fmt.Errorf("maximum depth exceeded")
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	// Pointers and interfaces add no nesting, so they do not count towards
+	// depth; a chain of them longer than fromMaxDepth can only be a cycle.
+	for hops := 0; rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface; hops++ {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if data, ok, err := fromSpecial(rv); ok {
+			return data, err
+		}
+		if hops == fromMaxDepth {
+			return nil, fmt.Errorf("maximum depth exceeded")
+		}
+		rv = rv.Elem()
+	}
+	if data, ok, err := fromSpecial(rv); ok {
+		return data, err
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return normalizeUint(rv.Uint()), nil
+	case reflect.Float32:
+		// Go through the shortest float32 text so 0.1 stays 0.1.
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(rv.Float(), 'g', -1, 32), 64)
+		return checkFloat(f)
+	case reflect.Float64:
+		return checkFloat(rv.Float())
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(rv.Bytes()), nil
+		}
+		return fromList(rv, depth)
+	case reflect.Array:
+		return fromList(rv, depth)
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return fromMap(rv, depth)
+	case reflect.Struct:
+		obj := map[string]any{}
+		if err := fromStruct(obj, rv, depth); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", rv.Type())
+	}
+}
+
+// normalizeUint keeps unsigned integers as int64 unless only uint64 can hold
+// them, as the parser does.
+func normalizeUint(u uint64) any {
+	if u > math.MaxInt64 {
+		return u
+	}
+	return int64(u)
+}
+
+// fromSpecial converts values that choose their own representation. Fields
+// reached through unexported embedded structs cannot be turned back into
+// interfaces, so they are only converted by kind.
+func fromSpecial(rv reflect.Value) (any, bool, error) {
+	if !rv.CanInterface() {
+		return nil, false, nil
+	}
+	var data any
+	var err error
+	switch val := rv.Interface().(type) {
+	case *Value:
+		if val.err != nil {
+			return nil, true, val.err
+		}
+		data = cloneData(val.data)
+	case *Builder:
+		data, err = val.build()
+	case *big.Int:
+		data = normalizeBig(val)
+	case json.Number:
+		data, err = fromNumber(val)
+	case json.Marshaler:
+		data, err = fromMarshaler(val)
+	case encoding.TextMarshaler:
+		var text []byte
+		text, err = val.MarshalText()
+		data = string(text)
+	default:
+		return nil, false, nil
+	}
+	return data, true, err
+}
+
+func checkFloat(f float64) (any, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("unsupported float %v", f)
+	}
+	return f, nil
+}
+
+func fromList(rv reflect.Value, depth int) (any, error) {
+	arr := make([]any, rv.Len())
+	for i := range arr {
+		elem, err := fromReflect(rv.Index(i), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = elem
+	}
+	return arr, nil
+}
+
+func fromMap(rv reflect.Value, depth int) (any, error) {
+	obj := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		elem, err := fromReflect(iter.Value(), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = elem
+	}
+	return obj, nil
+}
+
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if k.CanInterface() {
+		if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+			text, err := tm.MarshalText()
+			return string(text), err
+		}
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("unsupported map key type %s", k.Type())
+	}
+}
+
+// fromStruct adds the fields of rv to obj. Fields of embedded structs reached
+// through nil pointers are left out.
+func fromStruct(obj map[string]any, rv reflect.Value, depth int) error {
+fields:
+	for _, f := range structFields(rv.Type()) {
+		fv := rv
+		for _, i := range f.index {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue fields
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(i)
+		}
+		if hasOption(f.opts, "omitempty") && isEmptyValue(fv) {
+			continue
+		}
+
+		val, err := fromReflect(fv, depth+1)
+		if err != nil {
+			return err
+		}
+		if f.quoted {
+			switch val.(type) {
+			case string, bool, int64, uint64, float64:
+				text, err := appendJSON(nil, val)
+				if err != nil {
+					return err
+				}
+				val = string(text)
+			}
+		}
+		obj[f.name] = val
+	}
+	return nil
+}
+
+type structField struct {
+	name   string
+	opts   string
+	index  []int
+	tagged bool
+	// quoted is set when the string option applies, which like
+	// encoding/json depends on the declared type of the field.
+	quoted bool
+}
+
+var structFieldCache sync.Map // map[reflect.Type][]structField
+
+// structFields lists the fields encoding/json would write for t. Fields of
+// embedded structs are promoted, and when several fields share a name the
+// shallowest wins; among equally deep ones a single tagged field wins, and
+// otherwise all of them are dropped.
+func structFields(t reflect.Type) []structField {
+	if fields, ok := structFieldCache.Load(t); ok {
+		return fields.([]structField)
+	}
+
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var all []structField
+	visited := map[reflect.Type]bool{}
+	for next := []embedded{{typ: t}}; len(next) > 0; {
+		current := next
+		next = nil
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if !sf.IsExported() && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				index := append(append([]int(nil), e.index...), i)
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index})
+					continue
+				}
+				f := structField{name: name, opts: opts, index: index, tagged: name != ""}
+				f.quoted = hasOption(opts, "string") && quotable(sf.Type)
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				all = append(all, f)
+			}
+		}
+		// A type embedded twice at one level is expanded both times, so its
+		// fields conflict, but deeper copies are hidden by shallower ones.
+		for _, e := range current {
+			visited[e.typ] = true
+		}
+	}
+
+	byName := make(map[string][]structField)
+	for _, f := range all {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	var fields []structField
+	for _, f := range all {
+		if dominant, ok := dominantField(byName[f.name]); ok && equalIndex(dominant.index, f.index) {
+			fields = append(fields, f)
+		}
+	}
+	structFieldCache.Store(t, fields)
+	return fields
+}
+
+// quotable reports whether the string option applies to a field of type t:
+// strings, booleans and numbers, possibly behind one pointer.
+func quotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// dominantField picks the field that wins among fields sharing a name,
+// reporting false when they cancel each other out.
+func dominantField(fields []structField) (structField, bool) {
+	depth := len(fields[0].index)
+	for _, f := range fields {
+		if len(f.index) < depth {
+			depth = len(f.index)
+		}
+	}
+	var shallow, tagged []structField
+	for _, f := range fields {
+		if len(f.index) == depth {
+			shallow = append(shallow, f)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+	}
+	switch {
+	case len(tagged) == 1:
+		return tagged[0], true
+	case len(tagged) == 0 && len(shallow) == 1:
+		return shallow[0], true
+	default:
+		return structField{}, false
+	}
+}
+
+func equalIndex(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasOption(opts, name string) bool {
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,252 @@
+package jchain
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fromBase struct {
+	ID      int    `json:"id"`
+	Created string `json:"created,omitempty"`
+}
+
+type fromUser struct {
+	fromBase
+	Name     string            `json:"name"`
+	Email    *string           `json:"email"`
+	Tags     []string          `json:"tags,omitempty"`
+	Scores   map[string]uint64 `json:"scores"`
+	Count    int               `json:"count,string"`
+	Secret   string            `json:"-"`
+	ID       string            `json:"id"`
+	Avatar   []byte            `json:"avatar"`
+	IP       net.IP            `json:"ip"`
+	Ratio    float32           `json:"ratio"`
+	Untagged bool
+	private  int
+}
+
+func TestFrom(t *testing.T) {
+	u := fromUser{
+		fromBase: fromBase{ID: 7},
+		Name:     "Alice",
+		Scores:   map[string]uint64{"max": math.MaxUint64, "min": 1},
+		Count:    3,
+		Secret:   "hidden",
+		ID:       "u-7",
+		Avatar:   []byte("hi"),
+		IP:       net.IPv4(10, 0, 0, 1),
+		Ratio:    0.1,
+		private:  1,
+	}
+	want := Parse(`{
+		"id": "u-7",
+		"name": "Alice",
+		"email": null,
+		"scores": {"max": 18446744073709551615, "min": 1},
+		"count": "3",
+		"avatar": "aGk=",
+		"ip": "10.0.0.1",
+		"ratio": 0.1,
+		"Untagged": false
+	}`)
+	got := From(&u)
+	if err := got.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("got %v", got.data)
+	}
+	if _, ok := got.Get("scores").Get("min").data.(int64); !ok {
+		t.Errorf("expected small uint64 to be normalized to int64, got %T", got.Get("scores").Get("min").data)
+	}
+
+	scalars := []struct {
+		in   any
+		want string
+	}{
+		{nil, `null`},
+		{int8(-3), `-3`},
+		{uint(5), `5`},
+		{2.5, `2.5`},
+		{"x", `"x"`},
+		{[]int(nil), `null`},
+		{[2]bool{true, false}, `[true, false]`},
+		{map[int]string{1: "a"}, `{"1": "a"}`},
+		{Parse(`{"a": [1]}`), `{"a": [1]}`},
+		{[]any{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, `["2024-01-02T03:04:05Z"]`},
+	}
+	for _, tt := range scalars {
+		if got := From(tt.in); !got.Equal(Parse(tt.want)) {
+			t.Errorf("From(%#v) = %v, %v, want %s", tt.in, got.data, got.err, tt.want)
+		}
+	}
+
+	type node struct {
+		Next *node
+	}
+	loop := &node{}
+	loop.Next = loop
+	if err := From(loop).Error(); err == nil {
+		t.Error("expected error for a pointer cycle")
+	}
+	var self any
+	self = &self
+	if err := From(self).Error(); err == nil {
+		t.Error("expected error for an interface cycle")
+	}
+	if err := From(math.NaN()).Error(); err == nil {
+		t.Error("expected error for NaN")
+	}
+	if err := From(map[string]any{"f": func() {}}).Error(); err == nil || err.Error() != "unsupported type func()" {
+		t.Errorf("expected unsupported type, got %v", err)
+	}
+}
+
+type fromNamed struct {
+	Name string
+	Code int `json:"code"`
+}
+
+type fromLabel struct {
+	Name  string
+	Label string
+}
+
+type fromTagged struct {
+	Title string `json:"Name"`
+}
+
+type fromInner struct {
+	Deep string `json:"deep"`
+	Name string
+}
+
+type fromOuter struct {
+	fromInner
+}
+
+func TestFromEmbedded(t *testing.T) {
+	tests := []any{
+		// Name conflicts at the same depth and is dropped.
+		struct {
+			fromNamed
+			fromLabel
+		}{fromNamed{"a", 1}, fromLabel{"b", "c"}},
+		// The tagged Name wins over the untagged one at the same depth.
+		struct {
+			fromNamed
+			fromTagged
+		}{fromNamed{"a", 1}, fromTagged{"t"}},
+		// The shallower Name hides the conflicting deeper ones.
+		struct {
+			fromNamed
+			*fromOuter
+			Name string
+		}{fromNamed{"a", 1}, &fromOuter{fromInner{"d", "i"}}, "top"},
+		// Fields reached through a nil pointer are left out but still
+		// take part in conflicts.
+		struct {
+			*fromInner
+			fromNamed
+		}{nil, fromNamed{"a", 1}},
+		// The same type embedded twice conflicts with itself.
+		struct {
+			fromInner
+			fromOuter
+		}{fromInner{"x", "y"}, fromOuter{fromInner{"z", "w"}}},
+	}
+	for _, tt := range tests {
+		text, err := json.Marshal(tt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := From(tt); !got.Equal(Parse(string(text))) {
+			t.Errorf("From(%+v) = %v, %v, want %s", tt, got.data, got.err, text)
+		}
+	}
+}
+
+func TestFromStringOption(t *testing.T) {
+	n, s := 4, "x"
+	tests := []any{
+		struct {
+			A int     `json:"a,string"`
+			B *int    `json:"b,string"`
+			C *string `json:"c,string"`
+			D bool    `json:"d,string"`
+			E *int    `json:"e,string"`
+		}{1, &n, &s, true, nil},
+		// The option only applies by declared type: a time.Time is a
+		// struct and an interface holding a number is left alone.
+		struct {
+			T time.Time `json:"t,string"`
+			I any       `json:"i,string"`
+			S any       `json:"s,string"`
+		}{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), 7, "y"},
+	}
+	for _, tt := range tests {
+		text, err := json.Marshal(tt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := From(tt); !got.Equal(Parse(string(text))) {
+			t.Errorf("From(%+v) = %v, %v, want %s", tt, got.data, got.err, text)
+		}
+	}
+}
+
+func TestFromDepth(t *testing.T) {
+	nest := func(n int) any {
+		var val any = "x"
+		for i := 0; i < n; i++ {
+			val = []any{val}
+		}
+		return val
+	}
+	got := From(nest(999))
+	if err := got.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(Parse(strings.Repeat("[", 999) + `"x"` + strings.Repeat("]", 999))) {
+		t.Error("999-deep array does not match its parsed text")
+	}
+	if err := From(nest(1001)).Error(); err == nil {
+		t.Error("expected error beyond the depth limit")
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	b := NewObject().
+		Set("name", "Alice").
+		SetArray("tags", "a", 1, nil).
+		Set("address", NewObject().Set("city", "Paris")).
+		Set("items", NewArray(NewObject().Set("n", 1)).Append(2))
+	v := b.Value()
+	want := `{"name": "Alice", "tags": ["a", 1, null], "address": {"city": "Paris"}, "items": [{"n": 1}, 2]}`
+	if err := v.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if !v.Equal(Parse(want)) {
+		t.Errorf("got %v", v.data)
+	}
+
+	b.Set("name", "Bob")
+	if s, _ := v.Get("name").String(); s != "Alice" {
+		t.Error("expected built value to be unaffected by later changes")
+	}
+
+	if err := NewArray().Set("a", 1).Value().Error(); err == nil || err.Error() != "not object" {
+		t.Errorf("expected not object, got %v", err)
+	}
+	if err := NewObject().Append(1).Set("a", 1).Value().Error(); err == nil || err.Error() != "not array" {
+		t.Errorf("expected first error to be kept, got %v", err)
+	}
+	if err := NewObject().Set("bad", make(chan int)).Value().Error(); err == nil {
+		t.Error("expected error for unsupported value")
+	}
+}
@@ -0,0 +1,276 @@
+package jchain
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type StructOptions struct {
+	// Package, when set, emits a package clause before the types.
+	Package string
+	// Names overrides the generated type name for the object found at a
+	// dotted key path such as "user.address". Arrays are transparent, so
+	// "orders.items" names the element type of the items array.
+	Names map[string]string
+}
+
+type structGen struct {
+	opts     StructOptions
+	used     map[string]bool
+	declared map[string]bool
+	decls    []string
+	err      error
+}
+
+var commonInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "TCP": true, "TLS": true, "TTL": true,
+	"UID": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// GenerateStructs emits gofmt'ed Go type declarations able to hold every
+// sample, with the root type called name. Samples that are arrays of
+// objects, as list endpoints return, are described by their elements: the
+// root type is then the element type, to be decoded into a []name.
+func GenerateStructs(name string, opts StructOptions, samples ...*Value) (string, error) {
+	if !validTypeName(name) {
+		return "", fmt.Errorf("type name %q is not a Go identifier", name)
+	}
+	for _, n := range opts.Names {
+		if !validTypeName(n) {
+			return "", fmt.Errorf("type name %q is not a Go identifier", n)
+		}
+	}
+	s, err := inferShape(samples)
+	if err != nil {
+		return "", err
+	}
+	if !s.has(Object) && s.has(Array) && s.items != nil {
+		s = s.items
+	}
+	if !s.has(Object) {
+		return "", fmt.Errorf("root is not object or array of objects")
+	}
+
+	g := &structGen{opts: opts, used: make(map[string]bool), declared: make(map[string]bool)}
+	// Reserve the names given by the caller so that generated names never
+	// take them first.
+	g.used[name] = true
+	for _, n := range opts.Names {
+		g.used[n] = true
+	}
+	g.structType(name, "", s)
+	if g.err != nil {
+		return "", g.err
+	}
+
+	var buf bytes.Buffer
+	if opts.Package != "" {
+		fmt.Fprintf(&buf, "package %s\n\n", opts.Package)
+	}
+	buf.WriteString(strings.Join(g.decls, "\n"))
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return string(src), nil
+}
+
+func (g *structGen) structType(name string, path string, s *shape) {
+	if g.declared[name] {
+		g.err = fmt.Errorf("type name %s is used twice", name)
+		return
+	}
+	g.declared[name] = true
+	idx := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var body strings.Builder
+	fields := make(map[string]bool)
+	for _, key := range s.keys() {
+		field := uniqueName(goName(key), fields)
+		fields[field] = true
+
+		child := s.props[key]
+		typ := g.goType(joinPath(path, key), key, child)
+		if !validTag(key) && g.err == nil {
+			g.err = fmt.Errorf("key %q cannot be a json tag", key)
+		}
+		tag := key
+		if s.optional(key) {
+			tag += ",omitempty"
+		} else if key == "-" {
+			// A bare "-" tells encoding/json to skip the field.
+			tag += ","
+		}
+		fmt.Fprintf(&body, "\t%s %s `json:%s`\n", field, typ, strconv.Quote(tag))
+	}
+	g.decls[idx] = fmt.Sprintf("type %s struct {\n%s}\n", name, body.String())
+}
+
+func (g *structGen) goType(path string, key string, s *shape) string {
+	nullable := s.has(Null)
+	var kinds []Kind
+	for _, k := range []Kind{Object, Array, String, Int, Float, Bool} {
+		if s.has(k) {
+			kinds = append(kinds, k)
+		}
+	}
+
+	var typ string
+	switch {
+	case len(kinds) == 0:
+		return "any"
+	case len(kinds) == 2 && s.has(Int) && s.has(Float):
+		typ = "float64"
+	case len(kinds) > 1:
+		return "any"
+	default:
+		switch kinds[0] {
+		case Object:
+			typ = g.typeName(path, key)
+			g.structType(typ, path, s)
+		case Array:
+			elem := "any"
+			if s.items != nil {
+				elem = g.goType(path, singular(key), s.items)
+			}
+			return "[]" + elem
+		case String:
+			typ = "string"
+		case Int:
+			typ = "int64"
+			if s.unsigned {
+				typ = "uint64"
+				if s.negative {
+					typ = "float64"
+				}
+			}
+			if s.big {
+				typ = "float64"
+			}
+		case Float:
+			typ = "float64"
+		case Bool:
+			typ = "bool"
+		}
+	}
+
+	if nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+func (g *structGen) typeName(path string, key string) string {
+	if name, ok := g.opts.Names[path]; ok {
+		g.used[name] = true
+		return name
+	}
+	name := goName(key)
+	if g.used[name] {
+		name = goName(strings.ReplaceAll(path, ".", "_"))
+	}
+	name = uniqueName(name, g.used)
+	g.used[name] = true
+	return name
+}
+
+func validTypeName(name string) bool {
+	return token.IsIdentifier(name) && name != "_"
+}
+
+// validTag reports whether encoding/json accepts key as a tag name.
+func validTag(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", r):
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			return false
+		}
+	}
+	return true
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func singular(key string) string {
+	if strings.HasSuffix(key, "ies") && len(key) > 3 {
+		return key[:len(key)-3] + "y"
+	}
+	if strings.HasSuffix(key, "s") && !strings.HasSuffix(key, "ss") && len(key) > 1 {
+		return key[:len(key)-1]
+	}
+	return key
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	if !used[name] {
+		return name
+	}
+	for n := 2; ; n++ {
+		candidate := name + strconv.Itoa(n)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// goName turns a JSON key into an exported Go identifier, so that
+// "user_id" becomes UserID and "created-at" becomes CreatedAt.
+func goName(key string) string {
+	words := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var sb strings.Builder
+	for _, word := range words {
+		for _, part := range splitCamel(word) {
+			upper := strings.ToUpper(part)
+			if commonInitialisms[upper] {
+				sb.WriteString(upper)
+				continue
+			}
+			runes := []rune(part)
+			runes[0] = unicode.ToUpper(runes[0])
+			sb.WriteString(string(runes))
+		}
+	}
+
+	name := sb.String()
+	if name == "" {
+		return "Field"
+	}
+	if r := []rune(name)[0]; !unicode.IsLetter(r) {
+		name = "F" + name
+	}
+	return name
+}
+
+func splitCamel(word string) []string {
+	var parts []string
+	runes := []rune(word)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]) {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(parts, string(runes[start:]))
+}
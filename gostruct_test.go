@@ -0,0 +1,130 @@
+package jchain
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGenerateStructs(t *testing.T) {
+	samples := []*Value{
+		Parse(`{"user_id": 1, "score": 1, "address": {"city": "Oslo"}, "orders": [{"id": 1, "total": 9.5}], "nickname": null}`),
+		Parse(`{"user_id": 2, "score": 2.5, "address": null, "orders": [{"id": 2, "total": 10, "note": "gift"}], "nickname": "bob", "extra": true}`),
+	}
+
+	src, err := GenerateStructs("User", StructOptions{
+		Package: "api",
+		Names:   map[string]string{"orders": "Order"},
+	}, samples...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(src)
+
+	for _, want := range []string{
+		"package api",
+		"type User struct",
+		"UserID   int64",
+		"Score    float64",
+		"Address  *Address",
+		"Orders   []Order",
+		"Nickname *string",
+		"Extra    bool     `json:\"extra,omitempty\"`",
+		"type Address struct",
+		"type Order struct",
+		"Note  string  `json:\"note,omitempty\"`",
+		"Total float64",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated source missing %q", want)
+		}
+	}
+}
+
+func TestGenerateStructsArrayRoot(t *testing.T) {
+	src, err := GenerateStructs("Item", StructOptions{},
+		Parse(`[{"id": 1}, {"id": 2, "tags": ["a"]}]`),
+		Parse(`[{"id": 3}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"type Item struct", "ID   int64", "Tags []string `json:\"tags,omitempty\"`"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated source missing %q:\n%s", want, src)
+		}
+	}
+
+	for _, sample := range []string{`[1, 2]`, `[]`, `"x"`} {
+		_, err := GenerateStructs("T", StructOptions{}, Parse(sample))
+		if err == nil || err.Error() != "root is not object or array of objects" {
+			t.Errorf("%s: expected root error, got %v", sample, err)
+		}
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":    "UserID",
+		"created-at": "CreatedAt",
+		"homeURL":    "HomeURL",
+		"2fa":        "F2fa",
+		"":           "Field",
+	}
+	for key, want := range tests {
+		if got := goName(key); got != want {
+			t.Errorf("goName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestGenerateStructsNames(t *testing.T) {
+	// The generated name for "address" would be Address, which the caller
+	// gave to "home".
+	sample := Parse(`{"address": {"city": "Oslo"}, "home": {"street": "Main"}}`)
+	src, err := GenerateStructs("User", StructOptions{Names: map[string]string{"home": "Address"}}, sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Address Address2", "Home    Address", "type Address2 struct", "type Address struct"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated source missing %q:\n%s", want, src)
+		}
+	}
+
+	_, err = GenerateStructs("User", StructOptions{Names: map[string]string{"a": "T", "b": "T"}},
+		Parse(`{"a": {"x": 1}, "b": {"y": 1}}`))
+	if err == nil || err.Error() != "type name T is used twice" {
+		t.Errorf("expected duplicate type name error, got %v", err)
+	}
+
+	for _, name := range []string{"type", "1T", "_", "a-b", ""} {
+		_, err := GenerateStructs(name, StructOptions{}, Parse(`{"a": 1}`))
+		if want := "type name " + strconv.Quote(name) + " is not a Go identifier"; err == nil || err.Error() != want {
+			t.Errorf("%q: expected %q, got %v", name, want, err)
+		}
+	}
+	if _, err := GenerateStructs("T", StructOptions{Names: map[string]string{"a": "x.Y"}}, Parse(`{"a": {}}`)); err == nil {
+		t.Error("expected an error for an invalid name in Names")
+	}
+
+	src, err = GenerateStructs("T", StructOptions{},
+		Parse(`{"big": 18446744073709551615, "mixed": 18446744073709551615, "small": 1}`),
+		Parse(`{"big": 1, "mixed": -1, "small": -1}`))
+	for _, want := range []string{"Big   uint64", "Mixed float64", "Small int64"} {
+		if err != nil || !strings.Contains(src, want) {
+			t.Errorf("generated source missing %q, %v:\n%s", want, err, src)
+		}
+	}
+
+	src, err = GenerateStructs("T", StructOptions{}, Parse(`{"-": 1}`))
+	if err != nil || !strings.Contains(src, "`json:\"-,\"`") {
+		t.Errorf("expected key \"-\" to keep its field, got %v:\n%s", err, src)
+	}
+
+	for _, key := range []string{"a,b", `a"b`, "a`b", ""} {
+		sample := From(map[string]any{key: 1})
+		if _, err := GenerateStructs("T", StructOptions{}, sample); err == nil {
+			t.Errorf("%q: expected an error", key)
+		}
+	}
+}
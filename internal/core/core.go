@@ -0,0 +1,18 @@
+// Package core gives the format subpackages the constructors they share with
+// package jchain without making them part of its public API. Package jchain
+// sets the functions when it is initialized, which happens before any
+// package importing it runs.
+package core
+
+var (
+	// Value returns a *jchain.Value holding data, which must already be in
+	// the form the parser produces: map[string]any, []any, string, int64,
+	// uint64 (above math.MaxInt64 only), *big.Int (beyond uint64 and int64
+	// only), float64, bool or nil.
+	Value func(data any) any
+	// Error returns a *jchain.Value carrying err.
+	Error func(err error) any
+	// SyntaxError returns a *jchain.SyntaxError for msg at offset in input,
+	// with the same position and snippet as errors from jchain.Parse.
+	SyntaxError func(input string, offset int, msg string) error
+)
@@ -3,6 +3,7 @@ package jchain
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"unicode/utf8"
 )
 
@@ -14,7 +15,7 @@ func getKind(val any) Kind {
 		return Array
 	case string:
 		return String
-	case int, int64, uint64:
+	case int, int64, uint64, *big.Int:
 		return Int
 	case float64:
 		return Float
@@ -51,6 +52,55 @@ func ParseWithLimit(json string, maxDepth int) *Value {
 	return &Value{kind: getKind(res), data: res}
 }
 
+type ParseOptions struct {
+	// MaxDepth limits the nesting of objects and arrays. Zero means no
+	// limit, as with ParseUnlimited.
+	MaxDepth int
+	// Relaxed accepts // and /* */ comments and trailing commas.
+	Relaxed bool
+	// Positions records where every value starts and ends, see
+	// Value.Position. File is only used to label those positions.
+	Positions bool
+	File      string
+	// BigNumbers keeps integers that fit neither int64 nor uint64 exact as
+	// *big.Int, see Value.BigInt. Without it they become the nearest
+	// float64.
+	BigNumbers bool
+}
+
+func ParseWithOptions(json string, opts ParseOptions) *Value {
+	return parseWithOptions(newParser(json, opts), opts)
+}
+
+// ParseRecover parses json like ParseWithOptions but does not stop at the
+// first syntax error. It returns every error found together with a
+// best-effort tree in which values that failed to parse are replaced by
+// placeholders. Placeholders are null in the data seen by Array, Map, Any and
+// the encoders, but reaching one with Get or Index yields its error.
+func ParseRecover(json string, opts ParseOptions) (*Value, []*SyntaxError) {
+	p := newParser(json, opts)
+	p.recovering = true
+	v := parseWithOptions(p, opts)
+	if v.err == nil {
+		v.data, v.holes = takeHoles(v.data)
+	}
+	return v, p.errs
+}
+
+func parseWithOptions[T jsonText](p *parser[T], opts ParseOptions) *Value {
+	p.track = opts.Positions
+	res, err := p.parse()
+	if err != nil {
+		return &Value{err: err}
+	}
+	v := &Value{kind: getKind(res), data: res}
+	if opts.Positions {
+		v.span = p.root
+		v.src = newSource(opts.File, string(p.input))
+	}
+	return v
+}
+
 type Kind int
 
 const (
@@ -64,10 +114,50 @@ const (
 	Null
 )
 
+func (k Kind) String() string {
+	switch k {
+	case Object:
+		return "object"
+	case Array:
+		return "array"
+	case String:
+		return "string"
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case Bool:
+		return "boolean"
+	case Null:
+		return "null"
+	default:
+		return "invalid"
+	}
+}
+
 type Value struct {
 	kind Kind
 	data any
 	err  error
+
+	// span and src are only set when positions were requested.
+	span *span
+	src  *source
+
+	// holes is only set by ParseRecover, for values holding placeholders.
+	holes *hole
+}
+
+func (v *Value) child(val any, node *span, h *hole) *Value {
+	if h != nil && h.err != nil {
+		return &Value{err: h.err}
+	}
+	child := &Value{kind: getKind(val), data: val, holes: h}
+	if node != nil {
+		child.span = node
+		child.src = v.src
+	}
+	return child
 }
 
 func (v *Value) Index(i int) *Value {
@@ -84,8 +174,11 @@ func (v *Value) Index(i int) *Value {
 		if i < 0 || i >= len(arrSlice) {
 			return &Value{err: fmt.Errorf("index out of range")}
 		}
-		val := arrSlice[i]
-		return &Value{kind: getKind(val), data: val}
+		var node *span
+		if v.span != nil && i < len(v.span.children) {
+			node = v.span.children[i]
+		}
+		return v.child(arrSlice[i], node, v.holes.item(i))
 	} else {
 		return &Value{err: fmt.Errorf("invalid array structure")}
 	}
@@ -108,8 +201,22 @@ func (v *Value) Slice(start int, end int) *Value {
 			return &Value{err: fmt.Errorf("invalid index")}
 		}
 
-		slice := arr[start:end]
-		return &Value{kind: Array, data: slice}
+		slice := &Value{kind: Array, data: arr[start:end]}
+		if v.span != nil && end <= len(v.span.children) {
+			slice.span = &span{start: v.span.start, end: v.span.end, children: v.span.children[start:end]}
+			slice.src = v.src
+		}
+		if v.holes != nil {
+			for i := start; i < end; i++ {
+				if h := v.holes.item(i); h != nil {
+					if slice.holes == nil {
+						slice.holes = &hole{items: map[int]*hole{}}
+					}
+					slice.holes.items[i-start] = h
+				}
+			}
+		}
+		return slice
 	} else {
 		return &Value{err: fmt.Errorf("invalid array structure")}
 	}
@@ -134,8 +241,16 @@ func (v *Value) Get(key string) *Value {
 			}
 		}
 		if hasKey {
-			val := obj[key]
-			return &Value{kind: getKind(val), data: val}
+			var node *span
+			if v.span != nil {
+				for _, member := range v.span.children {
+					if member.key == key {
+						node = member
+						break
+					}
+				}
+			}
+			return v.child(obj[key], node, v.holes.member(key))
 		} else {
 			return &Value{err: fmt.Errorf("object doesnt have that key")}
 		}
@@ -161,6 +276,8 @@ func (v *Value) Int64() (int64, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return int64(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("invalid number")
 	}
@@ -186,6 +303,8 @@ func (v *Value) Int32() (int32, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return int32(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
@@ -211,6 +330,8 @@ func (v *Value) Int16() (int16, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return int16(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
@@ -236,6 +357,8 @@ func (v *Value) Int8() (int8, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return int8(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
@@ -261,6 +384,8 @@ func (v *Value) Int() (int, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return int(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
@@ -283,6 +408,8 @@ func (v *Value) Uint64() (uint64, error) {
 		return uint64(val), nil
 	case uint64:
 		return val, nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
@@ -308,6 +435,8 @@ func (v *Value) Uint32() (uint32, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return uint32(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
@@ -333,6 +462,8 @@ func (v *Value) Uint16() (uint16, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return uint16(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
@@ -358,6 +489,8 @@ func (v *Value) Uint8() (uint8, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return uint8(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
@@ -383,6 +516,8 @@ func (v *Value) Uint() (uint, error) {
 			return 0, fmt.Errorf("out of range")
 		}
 		return uint(val), nil
+	case *big.Int:
+		return 0, fmt.Errorf("out of range")
 	default:
 		return 0, fmt.Errorf("not int")
 	}
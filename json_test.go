@@ -64,7 +64,7 @@ func TestMaxDepth(t *testing.T) {
 	if parsed.Error() == nil {
 		t.Fatal("expected error for exceeding max depth")
 	}
-	if !strings.Contains(parsed.Error().Error(), "Maximum depth exceeded") {
+	if !strings.Contains(parsed.Error().Error(), "maximum depth exceeded") {
 		t.Fatalf("unexpected error message: %v", parsed.Error())
 	}
 
@@ -122,3 +122,53 @@ func TestErrorMessage(t *testing.T) {
 		t.Errorf("expected error to mention line 6, got: %s", errMsg)
 	}
 }
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		json, msg, found string
+		line, col        int
+		opened           bool
+	}{
+		{`{"a": [1, 2`, "expected ',' or ']' after array element", "end of input", 1, 12, true},
+		{`{"a" 1}`, "expected ':' after object key", "'1'", 1, 6, false},
+		{`{"a": 1 "b": 2}`, "expected ',' or '}' after object member", `'"'`, 1, 9, false},
+		{"[\n  \"abc", "unterminated string", "end of input", 2, 7, true},
+		{`[1, tru]`, "invalid literal, expected 'true'", "'tru'", 1, 5, false},
+		{`[1] x`, "unexpected data after top-level value", "'x'", 1, 5, false},
+		{`"\x"`, "invalid escape sequence", `'\'`, 1, 2, false},
+		{`["\ud800"]`, "expected low surrogate after high surrogate", `'"'`, 1, 9, false},
+	}
+	for _, tt := range tests {
+		err := Parse(tt.json).Error()
+		synErr, ok := err.(*SyntaxError)
+		if !ok {
+			t.Fatalf("%s: expected *SyntaxError, got %v", tt.json, err)
+		}
+		if synErr.Msg != tt.msg || synErr.Found != tt.found || synErr.Line != tt.line || synErr.Column != tt.col {
+			t.Errorf("%s: unexpected error %q, found %s at %d:%d", tt.json, synErr.Msg, synErr.Found, synErr.Line, synErr.Column)
+		}
+		if (synErr.Opened != nil) != tt.opened {
+			t.Errorf("%s: unexpected opened position %v", tt.json, synErr.Opened)
+		}
+	}
+
+	err := Parse("{\n\t\"list\": [1,\n\t\twhat]\n}").Error()
+	want := "expected value, found 'what' at line 3, column 3\n\t\twhat]\n\t\t^"
+	if err.Error() != want {
+		t.Errorf("unexpected message:\n%s\nwant:\n%s", err, want)
+	}
+
+	err = Parse(`{"list": [1, 2`).Error()
+	if !strings.Contains(err.Error(), "(array opened at line 1, column 10)") {
+		t.Errorf("expected opening position in message, got: %s", err)
+	}
+
+	long := "[" + strings.Repeat("1, ", 100) + "x]"
+	synErr := Parse(long).Error().(*SyntaxError)
+	if len(synErr.Snippet) > 2*snippetWidth+6 || !strings.HasPrefix(synErr.Snippet, "...") {
+		t.Errorf("expected truncated snippet, got %q", synErr.Snippet)
+	}
+	if synErr.Snippet[len(synErr.Caret)-1] != 'x' {
+		t.Errorf("caret does not point at the error:\n%s\n%s", synErr.Snippet, synErr.Caret)
+	}
+}
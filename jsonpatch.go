@@ -0,0 +1,378 @@
+package jchain
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ApplyPatch applies an RFC 6902 JSON Patch document to doc. The patch is
+// applied to a copy, so if any operation fails doc is left untouched and the
+// result carries the error.
+func ApplyPatch(doc, patch *Value) *Value {
+	if doc.err != nil {
+		return &Value{err: doc.err}
+	}
+	if patch.err != nil {
+		return &Value{err: patch.err}
+	}
+
+	ops, ok := patch.data.([]any)
+	if !ok {
+		return &Value{err: fmt.Errorf("patch is not array")}
+	}
+
+	res := cloneData(doc.data)
+	for i, op := range ops {
+		var err error
+		res, err = applyOperation(res, op)
+		if err != nil {
+			return &Value{err: fmt.Errorf("operation %d: %w", i, err)}
+		}
+	}
+	return &Value{kind: getKind(res), data: res}
+}
+
+func applyOperation(doc any, op any) (any, error) {
+	obj, ok := op.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("not object")
+	}
+	name, err := operationString(obj, "op")
+	if err != nil {
+		return nil, err
+	}
+	path, err := operationPointer(obj, "path")
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "add":
+		val, ok := obj["value"]
+		if !ok {
+			return nil, fmt.Errorf("missing value")
+		}
+		return patchAdd(doc, path, cloneData(val))
+	case "remove":
+		res, _, err := patchRemove(doc, path)
+		return res, err
+	case "replace":
+		val, ok := obj["value"]
+		if !ok {
+			return nil, fmt.Errorf("missing value")
+		}
+		res, _, err := patchRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return patchAdd(res, path, cloneData(val))
+	case "move":
+		from, err := operationPointer(obj, "from")
+		if err != nil {
+			return nil, err
+		}
+		if len(from) < len(path) && formatPointer(path[:len(from)]) == formatPointer(from) {
+			return nil, fmt.Errorf("cannot move a value into one of its children")
+		}
+		res, val, err := patchRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return patchAdd(res, path, val)
+	case "copy":
+		from, err := operationPointer(obj, "from")
+		if err != nil {
+			return nil, err
+		}
+		val, err := lookupPointer(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return patchAdd(doc, path, cloneData(val))
+	case "test":
+		want, ok := obj["value"]
+		if !ok {
+			return nil, fmt.Errorf("missing value")
+		}
+		val, err := lookupPointer(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equalWith(val, want, NumericNumbers) {
+			return nil, fmt.Errorf("test failed at %q", formatPointer(path))
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown op %q", name)
+	}
+}
+
+func operationString(obj map[string]any, key string) (string, error) {
+	val, ok := obj[key]
+	if !ok {
+		return "", fmt.Errorf("missing %s", key)
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("%s is not string", key)
+	}
+	return s, nil
+}
+
+func operationPointer(obj map[string]any, key string) ([]string, error) {
+	ptr, err := operationString(obj, key)
+	if err != nil {
+		return nil, err
+	}
+	return parsePointer(ptr)
+}
+
+func patchAdd(node any, path []string, val any) (any, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	token := path[0]
+
+	switch n := node.(type) {
+	case map[string]any:
+		if len(path) == 1 {
+			n[token] = val
+			return n, nil
+		}
+		child, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("object doesnt have key %q", token)
+		}
+		child, err := patchAdd(child, path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = child
+		return n, nil
+	case []any:
+		if len(path) == 1 {
+			idx := len(n)
+			if token != "-" {
+				var err error
+				idx, err = arrayIndex(token, len(n)+1)
+				if err != nil {
+					return nil, err
+				}
+			}
+			n = append(n, nil)
+			copy(n[idx+1:], n[idx:])
+			n[idx] = val
+			return n, nil
+		}
+		idx, err := arrayIndex(token, len(n))
+		if err != nil {
+			return nil, err
+		}
+		child, err := patchAdd(n[idx], path[1:], val)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = child
+		return n, nil
+	default:
+		return nil, fmt.Errorf("cannot index %s with %q", getKind(node), token)
+	}
+}
+
+func patchRemove(node any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, node, nil
+	}
+	token := path[0]
+
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("object doesnt have key %q", token)
+		}
+		if len(path) == 1 {
+			delete(n, token)
+			return n, child, nil
+		}
+		child, removed, err := patchRemove(child, path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[token] = child
+		return n, removed, nil
+	case []any:
+		idx, err := arrayIndex(token, len(n))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := n[idx]
+			return append(n[:idx], n[idx+1:]...), removed, nil
+		}
+		child, removed, err := patchRemove(n[idx], path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[idx] = child
+		return n, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot index %s with %q", getKind(node), token)
+	}
+}
+
+// Diff returns a JSON Patch document that turns a into b. Arrays are compared
+// element by element; use DiffLCS for insertions and deletions in the middle
+// of arrays.
+func Diff(a, b *Value) *Value {
+	return diffValues(a, b, false)
+}
+
+// DiffLCS is like Diff but aligns array elements using their longest common
+// subsequence, which keeps patches small when elements are inserted or
+// removed.
+func DiffLCS(a, b *Value) *Value {
+	return diffValues(a, b, true)
+}
+
+func diffValues(a, b *Value, lcs bool) *Value {
+	if a.err != nil {
+		return &Value{err: a.err}
+	}
+	if b.err != nil {
+		return &Value{err: b.err}
+	}
+
+	d := &differ{lcs: lcs, ops: make([]any, 0)}
+	d.diff(nil, a.data, b.data)
+	return &Value{kind: Array, data: d.ops}
+}
+
+type differ struct {
+	lcs bool
+	ops []any
+}
+
+func (d *differ) emit(op string, path []string, val any, withValue bool) {
+	obj := map[string]any{"op": op, "path": formatPointer(path)}
+	if withValue {
+		obj["value"] = cloneData(val)
+	}
+	d.ops = append(d.ops, obj)
+}
+
+func (d *differ) diff(path []string, a, b any) {
+	if equalData(a, b) {
+		return
+	}
+
+	switch a := a.(type) {
+	case map[string]any:
+		if b, ok := b.(map[string]any); ok {
+			d.diffObjects(path, a, b)
+			return
+		}
+	case []any:
+		if b, ok := b.([]any); ok {
+			if d.lcs {
+				d.diffArraysLCS(path, a, b)
+			} else {
+				d.diffArrays(path, a, b)
+			}
+			return
+		}
+	}
+	d.emit("replace", path, b, true)
+}
+
+func (d *differ) diffObjects(path []string, a, b map[string]any) {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child := appendPath(path, key)
+		aVal, inA := a[key]
+		bVal, inB := b[key]
+		switch {
+		case !inB:
+			d.emit("remove", child, nil, false)
+		case !inA:
+			d.emit("add", child, bVal, true)
+		default:
+			d.diff(child, aVal, bVal)
+		}
+	}
+}
+
+func (d *differ) diffArrays(path []string, a, b []any) {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		d.diff(appendPath(path, strconv.Itoa(i)), a[i], b[i])
+	}
+	for i := n; i < len(b); i++ {
+		d.emit("add", appendPath(path, strconv.Itoa(i)), b[i], true)
+	}
+	for i := len(a) - 1; i >= n; i-- {
+		d.emit("remove", appendPath(path, strconv.Itoa(i)), nil, false)
+	}
+}
+
+func (d *differ) diffArraysLCS(path []string, a, b []any) {
+	// table[i][j] is the length of the LCS of a[i:] and b[j:].
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if equalData(a[i], b[j]) {
+				table[i][j] = table[i+1][j+1] + 1
+			} else if table[i+1][j] >= table[i][j+1] {
+				table[i][j] = table[i+1][j]
+			} else {
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	i, j, pos := 0, 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && equalData(a[i], b[j]):
+			i++
+			j++
+			pos++
+		case i < len(a) && j < len(b) && table[i][j] == table[i+1][j+1]:
+			d.diff(appendPath(path, strconv.Itoa(pos)), a[i], b[j])
+			i++
+			j++
+			pos++
+		case j < len(b) && (i == len(a) || table[i][j+1] > table[i+1][j]):
+			d.emit("add", appendPath(path, strconv.Itoa(pos)), b[j], true)
+			j++
+			pos++
+		default:
+			d.emit("remove", appendPath(path, strconv.Itoa(pos)), nil, false)
+			i++
+		}
+	}
+}
+
+func appendPath(path []string, token string) []string {
+	res := make([]string, len(path)+1)
+	copy(res, path)
+	res[len(path)] = token
+	return res
+}
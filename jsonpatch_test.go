@@ -0,0 +1,98 @@
+package jchain
+
+import (
+	"strings"
+	"testing"
+)
+
+// Test cases from RFC 6902, Appendix A.
+var applyPatchTests = []struct {
+	doc, patch, result string
+}{
+	{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+	{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+	{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+	{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+	{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+	{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+	{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+	{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+	{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+	{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`},
+	{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+	{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+	{`{"foo":"bar"}`, `[{"op":"copy","from":"/foo","path":"/baz"}]`, `{"foo":"bar","baz":"bar"}`},
+	{`{"a":1}`, `[{"op":"test","path":"/a","value":1.0}]`, `{"a":1}`},
+	{`{"a":100}`, `[{"op":"test","path":"/a","value":1e2}]`, `{"a":100}`},
+}
+
+var applyPatchErrorTests = []struct {
+	doc, patch string
+}{
+	{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+	{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`},
+	{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`},
+	{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/01","value":1}]`},
+	{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`},
+	{`{"foo":1}`, `[{"op":"frob","path":"/foo"}]`},
+	{`{"a":1}`, `[{"op":"test","path":"/a","value":1.5}]`},
+}
+
+func TestApplyPatch(t *testing.T) {
+	for _, tt := range applyPatchTests {
+		res := ApplyPatch(Parse(tt.doc), Parse(tt.patch))
+		if err := res.Error(); err != nil {
+			t.Errorf("ApplyPatch(%s, %s): %v", tt.doc, tt.patch, err)
+			continue
+		}
+		if !equalData(res.data, Parse(tt.result).data) {
+			t.Errorf("ApplyPatch(%s, %s) = %v, want %s", tt.doc, tt.patch, res.data, tt.result)
+		}
+	}
+	for _, tt := range applyPatchErrorTests {
+		if err := ApplyPatch(Parse(tt.doc), Parse(tt.patch)).Error(); err == nil {
+			t.Errorf("ApplyPatch(%s, %s): expected error", tt.doc, tt.patch)
+		}
+	}
+}
+
+func TestApplyPatchAtomic(t *testing.T) {
+	doc := Parse(`{"a":[1,2,3],"b":{"c":1}}`)
+	patch := Parse(`[{"op":"remove","path":"/a/0"},{"op":"replace","path":"/b/c","value":2},{"op":"remove","path":"/missing"}]`)
+	if err := ApplyPatch(doc, patch).Get("a").Error(); err == nil || !strings.HasPrefix(err.Error(), "operation 2: ") {
+		t.Fatalf("expected the error of operation 2 to carry down the chain, got %v", err)
+	}
+	if !equalData(doc.data, Parse(`{"a":[1,2,3],"b":{"c":1}}`).data) {
+		t.Errorf("document modified by failed patch: %v", doc.data)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	pairs := [][2]string{
+		{`{"a":1,"b":[1,2,3],"c":{"d":true}}`, `{"a":2,"b":[1,3],"c":{"e":null}}`},
+		{`[1,2,3,4,5]`, `[0,1,2,4,5,6]`},
+		{`{"list":[{"id":1},{"id":2},{"id":3}]}`, `{"list":[{"id":0},{"id":1},{"id":3,"x":1}]}`},
+		{`"x"`, `{"a":[]}`},
+	}
+	for _, pair := range pairs {
+		a, b := Parse(pair[0]), Parse(pair[1])
+		for _, diff := range []func(a, b *Value) *Value{Diff, DiffLCS} {
+			patch := diff(a, b)
+			if err := patch.Error(); err != nil {
+				t.Fatal(err)
+			}
+			res := ApplyPatch(a, patch)
+			if err := res.Error(); err != nil {
+				t.Fatalf("applying diff of %s and %s: %v", pair[0], pair[1], err)
+			}
+			if !equalData(res.data, b.data) {
+				t.Errorf("diff of %s and %s produced %v, which gives %v", pair[0], pair[1], patch.data, res.data)
+			}
+		}
+	}
+
+	ops, _ := DiffLCS(Parse(`[1,2,3,4,5]`), Parse(`[0,1,2,3,4,5]`)).Array()
+	if len(ops) != 1 {
+		t.Errorf("expected a single add from DiffLCS, got %v", ops)
+	}
+}
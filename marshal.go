@@ -0,0 +1,83 @@
+package jchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MarshalJSON implements json.Marshaler so a *Value can be embedded in data
+// encoded with encoding/json. Floats keep a fraction or exponent so that they
+// read back as floats.
+func (v *Value) MarshalJSON() ([]byte, error) {
+	if v == nil {
+		return []byte("null"), nil
+	}
+	if v.err != nil {
+		return nil, v.err
+	}
+	return appendJSON(nil, v.data)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It parses data without copying
+// it, and the result does not refer to data. On error v is left unchanged.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	res, err := parseJSON(data, 1000)
+	if err != nil {
+		return err
+	}
+	*v = Value{kind: getKind(res), data: res}
+	return nil
+}
+
+// RawMessage returns the value encoded as compact JSON.
+func (v *Value) RawMessage() (json.RawMessage, error) {
+	return v.MarshalJSON()
+}
+
+// Number returns an Int or Float as a json.Number. Its text parses back to
+// the same kind.
+func (v *Value) Number() (json.Number, error) {
+	if v.err != nil {
+		return "", v.err
+	}
+
+	switch val := v.data.(type) {
+	case int:
+		return json.Number(strconv.Itoa(val)), nil
+	case int64:
+		return json.Number(strconv.FormatInt(val, 10)), nil
+	case uint64:
+		return json.Number(strconv.FormatUint(val, 10)), nil
+	case *big.Int:
+		return json.Number(val.String()), nil
+	case float64:
+		text, err := appendFloat(nil, val)
+		if err != nil {
+			return "", err
+		}
+		return json.Number(text), nil
+	default:
+		return "", fmt.Errorf("not number")
+	}
+}
+
+// fromNumber converts a json.Number the way the parser converts numbers.
+func fromNumber(n json.Number) (any, error) {
+	if !validNumber([]byte(n)) {
+		return nil, fmt.Errorf("invalid number %q", string(n))
+	}
+	return numberValue(string(n), !strings.ContainsAny(string(n), ".eE"), false)
+}
+
+// fromMarshaler parses the output of a json.Marshaler, including
+// json.RawMessage.
+func fromMarshaler(m json.Marshaler) (any, error) {
+	text, err := m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return parseJSON(text, 1000)
+}
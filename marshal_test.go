@@ -0,0 +1,133 @@
+package jchain
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValueMarshalJSON(t *testing.T) {
+	type envelope struct {
+		ID      int    `json:"id"`
+		Payload *Value `json:"payload"`
+		Missing *Value `json:"missing"`
+	}
+
+	var env envelope
+	input := `{"id": 1, "payload": {"f": 1.0, "i": 2, "big": 18446744073709551615, "s": "x"}, "missing": null}`
+	if err := json.Unmarshal([]byte(input), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Payload.Get("f").Kind() != Float || env.Payload.Get("i").Kind() != Int {
+		t.Errorf("expected number kinds to be preserved, got %v and %v", env.Payload.Get("f").Kind(), env.Payload.Get("i").Kind())
+	}
+	if u, err := env.Payload.Get("big").Uint64(); err != nil || u != 18446744073709551615 {
+		t.Errorf("got %d, %v", u, err)
+	}
+	// encoding/json leaves pointers nil for null without calling UnmarshalJSON.
+	if env.Missing != nil {
+		t.Errorf("expected nil, got %v", env.Missing.data)
+	}
+
+	out, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":1,"payload":{"big":18446744073709551615,"f":1.0,"i":2,"s":"x"},"missing":null}`
+	if string(out) != want {
+		t.Errorf("got %s, want %s", out, want)
+	}
+
+	out, err = json.Marshal(struct{ V *Value }{})
+	if err != nil || string(out) != `{"V":null}` {
+		t.Errorf("expected nil *Value to marshal as null, got %s, %v", out, err)
+	}
+	if _, err := json.Marshal(Parse(`{}`).Get("x")); err == nil || !strings.Contains(err.Error(), "object doesnt have that key") {
+		t.Errorf("expected chain error, got %v", err)
+	}
+
+	v := Parse(`"keep"`)
+	if err := v.UnmarshalJSON([]byte(`{"a": `)); err == nil {
+		t.Error("expected syntax error")
+	}
+	if s, _ := v.String(); s != "keep" {
+		t.Error("expected value to be unchanged after a failed unmarshal")
+	}
+
+	// The bytes are parsed in place, so neither the value nor an error may
+	// refer to them afterwards.
+	buf := []byte(`{"key": "text", "n": 12}`)
+	if err := v.UnmarshalJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	bad := []byte(`[1, x]`)
+	err = v.UnmarshalJSON(bad)
+	for i := range buf {
+		buf[i] = '#'
+	}
+	for i := range bad {
+		bad[i] = '#'
+	}
+	if !v.Equal(Parse(`{"key": "text", "n": 12}`)) {
+		t.Errorf("value changed with its input: %v", v.data)
+	}
+	if se, ok := err.(*SyntaxError); !ok || se.Snippet != "[1, x]" || se.Found != "'x'" {
+		t.Errorf("error changed with its input: %#v", err)
+	}
+}
+
+func TestRawMessageAndNumber(t *testing.T) {
+	raw := json.RawMessage(`{"n": 1.50, "list": [1, 2e3]}`)
+	v := From(raw)
+	if err := v.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if v.Get("list").Index(1).Kind() != Float {
+		t.Errorf("expected 2e3 to stay a float")
+	}
+	back, err := v.RawMessage()
+	if err != nil || string(back) != `{"list":[1,2000.0],"n":1.5}` {
+		t.Errorf("got %s, %v", back, err)
+	}
+
+	numbers := []struct {
+		in   json.Number
+		kind Kind
+		out  json.Number
+	}{
+		{"42", Int, "42"},
+		{"-7", Int, "-7"},
+		{"1.0", Float, "1.0"},
+		{"1e2", Float, "100.0"},
+		{"18446744073709551615", Int, "18446744073709551615"},
+		{"1e400", Invalid, ""},
+		{"01", Invalid, ""},
+	}
+	for _, tt := range numbers {
+		v := From(tt.in)
+		if v.Kind() != tt.kind {
+			t.Errorf("From(%s): kind %v, want %v (%v)", tt.in, v.Kind(), tt.kind, v.Error())
+			continue
+		}
+		if tt.kind == Invalid {
+			continue
+		}
+		n, err := v.Number()
+		if err != nil || n != tt.out {
+			t.Errorf("From(%s).Number() = %s, %v, want %s", tt.in, n, err, tt.out)
+		}
+	}
+	if _, err := Parse(`"1"`).Number(); err == nil || err.Error() != "not number" {
+		t.Errorf("expected not number, got %v", err)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(`{"a": 1, "b": 1.0}`))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if got := From(m); got.Get("a").Kind() != Int || got.Get("b").Kind() != Float {
+		t.Errorf("expected kinds to survive UseNumber decoding, got %v", got.data)
+	}
+}
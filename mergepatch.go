@@ -0,0 +1,142 @@
+package jchain
+
+import (
+	"fmt"
+)
+
+// MergePatch applies an RFC 7386 merge patch to target and returns the
+// result. Neither argument is modified.
+func MergePatch(target, patch *Value) *Value {
+	if target.err != nil {
+		return &Value{err: target.err}
+	}
+	if patch.err != nil {
+		return &Value{err: patch.err}
+	}
+
+	res := mergePatch(target.data, patch.data)
+	return &Value{kind: getKind(res), data: res}
+}
+
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return cloneData(patch)
+	}
+
+	res := make(map[string]any)
+	if targetObj, ok := target.(map[string]any); ok {
+		for key, val := range targetObj {
+			res[key] = val
+		}
+	}
+	for key, val := range patchObj {
+		if val == nil {
+			delete(res, key)
+		} else {
+			res[key] = mergePatch(res[key], val)
+		}
+	}
+	for key, val := range res {
+		if _, patched := patchObj[key]; !patched {
+			res[key] = cloneData(val)
+		}
+	}
+	return res
+}
+
+// CreateMergePatch returns the smallest merge patch that turns original into
+// modified. Merge patches cannot set a member to null, so a modified document
+// that does so, directly or inside an added object, results in an error.
+func CreateMergePatch(original, modified *Value) *Value {
+	if original.err != nil {
+		return &Value{err: original.err}
+	}
+	if modified.err != nil {
+		return &Value{err: modified.err}
+	}
+
+	res, err := createMergePatch(original.data, modified.data)
+	if err != nil {
+		return &Value{err: err}
+	}
+	return &Value{kind: getKind(res), data: res}
+}
+
+func createMergePatch(original, modified any) (any, error) {
+	origObj, ok1 := original.(map[string]any)
+	modObj, ok2 := modified.(map[string]any)
+	if !ok1 || !ok2 {
+		if err := checkNullMembers(modified); err != nil {
+			return nil, err
+		}
+		return cloneData(modified), nil
+	}
+
+	patch := make(map[string]any)
+	for key := range origObj {
+		if _, ok := modObj[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	for key, modVal := range modObj {
+		origVal, ok := origObj[key]
+		if ok && equalData(origVal, modVal) {
+			continue
+		}
+		if modVal == nil {
+			return nil, fmt.Errorf("cannot set %q to null with merge patch", key)
+		}
+		if !ok {
+			if err := checkNullMembers(modVal); err != nil {
+				return nil, err
+			}
+			patch[key] = cloneData(modVal)
+			continue
+		}
+		sub, err := createMergePatch(origVal, modVal)
+		if err != nil {
+			return nil, err
+		}
+		patch[key] = sub
+	}
+	return patch, nil
+}
+
+// checkNullMembers reports a null member in val or its nested objects, which
+// a merge patch would read as a deletion. Arrays replace the target whole, so
+// their items may be null.
+func checkNullMembers(val any) error {
+	obj, ok := val.(map[string]any)
+	if !ok {
+		return nil
+	}
+	for key, elem := range obj {
+		if elem == nil {
+			return fmt.Errorf("cannot set %q to null with merge patch", key)
+		}
+		if err := checkNullMembers(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cloneData(val any) any {
+	switch val := val.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(val))
+		for key, elem := range val {
+			res[key] = cloneData(elem)
+		}
+		return res
+	case []any:
+		res := make([]any, len(val))
+		for i, elem := range val {
+			res[i] = cloneData(elem)
+		}
+		return res
+	default:
+		return val
+	}
+}
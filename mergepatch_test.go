@@ -0,0 +1,80 @@
+package jchain
+
+import (
+	"testing"
+)
+
+// Test cases from RFC 7386, Appendix A.
+var mergePatchTests = []struct {
+	target, patch, result string
+}{
+	{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+	{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+	{`{"a":"b"}`, `{"a":null}`, `{}`},
+	{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+	{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+	{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+	{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+	{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+	{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+	{`{"a":"b"}`, `["c"]`, `["c"]`},
+	{`{"a":"foo"}`, `null`, `null`},
+	{`{"a":"foo"}`, `"bar"`, `"bar"`},
+	{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+	{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+	{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+}
+
+func TestMergePatch(t *testing.T) {
+	for _, tt := range mergePatchTests {
+		target := Parse(tt.target)
+		res := MergePatch(target, Parse(tt.patch))
+		if err := res.Error(); err != nil {
+			t.Fatal(err)
+		}
+		if !equalData(res.data, Parse(tt.result).data) {
+			t.Errorf("MergePatch(%s, %s) = %v, want %s", tt.target, tt.patch, res.data, tt.result)
+		}
+		if !equalData(target.data, Parse(tt.target).data) {
+			t.Errorf("MergePatch modified target %s", tt.target)
+		}
+	}
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	original := Parse(`{"title":"Hello!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`)
+	modified := Parse(`{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`)
+
+	patch := CreateMergePatch(original, modified)
+	if err := patch.Error(); err != nil {
+		t.Fatal(err)
+	}
+	want := Parse(`{"author":{"familyName":null},"tags":["example"],"phoneNumber":"+01-123-456-7890"}`)
+	if !equalData(patch.data, want.data) {
+		t.Errorf("unexpected patch: %v", patch.data)
+	}
+	if res := MergePatch(original, patch); !equalData(res.data, modified.data) {
+		t.Errorf("applying created patch gave %v", res.data)
+	}
+
+	if CreateMergePatch(Parse(`{"a":1}`), Parse(`{"a":null}`)).Error() == nil {
+		t.Error("expected error when setting a member to null")
+	}
+
+	// Nulls inside added members would be dropped when the patch is applied.
+	for _, tt := range [][2]string{
+		{`{}`, `{"a":{"b":null}}`},
+		{`{"a":1}`, `{"a":{"b":{"c":null}}}`},
+		{`[1]`, `{"a":null}`},
+	} {
+		if err := CreateMergePatch(Parse(tt[0]), Parse(tt[1])).Error(); err == nil {
+			t.Errorf("%s to %s: expected error for nested null", tt[0], tt[1])
+		}
+	}
+	original = Parse(`{}`)
+	modified = Parse(`{"a":{"b":[null, {"c":1}]}}`)
+	patch = CreateMergePatch(original, modified)
+	if res := MergePatch(original, patch); !equalData(res.data, modified.data) {
+		t.Errorf("null in an array: applying %v gave %v, %v", patch.data, res.data, patch.Error())
+	}
+}
@@ -0,0 +1,468 @@
+// Package msgpack converts between MessagePack and jchain values, so that
+// MessagePack payloads can be traversed with the same API as JSON.
+//
+// Decoding maps every integer family onto int64, or uint64 above
+// math.MaxInt64, and both float families onto float64, as the JSON parser
+// does. Binary data becomes a standard base64 string and timestamps become
+// RFC 3339 strings, matching how Value.Bytes and Value.Time read them back.
+// Other extension types must be handled by DecodeOptions.Ext.
+//
+// A Value has no binary, timestamp or extension kind, so this mapping is one
+// way: Encode writes those strings as str, and whatever DecodeOptions.Ext
+// returned as the MessagePack type of its jchain value.
+package msgpack
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mntwlds/jchain"
+	"github.com/mntwlds/jchain/internal/core"
+)
+
+// timestampExt is the extension type reserved for timestamps.
+const timestampExt = -1
+
+type DecodeOptions struct {
+	// MaxDepth limits the nesting of maps and arrays. Zero means no limit,
+	// as in jchain.ParseOptions.
+	MaxDepth int
+	// Ext converts extension types other than timestamps. Its result is
+	// converted with jchain.From. Without it such extensions are an error.
+	Ext func(typ int8, data []byte) (any, error)
+}
+
+// Decode reads a single MessagePack value, nested at most as deep as
+// jchain.Parse allows.
+func Decode(data []byte) *jchain.Value {
+	return DecodeWithOptions(data, DecodeOptions{MaxDepth: 1000})
+}
+
+func DecodeWithOptions(data []byte, opts DecodeOptions) *jchain.Value {
+	d := &decoder{data: data, opts: opts}
+	val, err := d.value()
+	if err == nil && d.off < len(d.data) {
+		err = fmt.Errorf("unexpected data after value at offset %d", d.off)
+	}
+	if err != nil {
+		return core.Error(err).(*jchain.Value)
+	}
+	return core.Value(val).(*jchain.Value)
+}
+
+type decoder struct {
+	data  []byte
+	off   int
+	depth int
+	opts  DecodeOptions
+}
+
+func (d *decoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.off < n {
+		return nil, fmt.Errorf("unexpected end of input at offset %d", len(d.data))
+	}
+	b := d.data[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+func (d *decoder) uint(n int) (uint64, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *decoder) value() (any, error) {
+	start := d.off
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	c := b[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.mapN(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.arrayN(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(int(n), start)
+	case 0xca:
+		u, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		// Go through the shortest float32 text so 0.1 stays 0.1.
+		f := math.Float32frombits(uint32(u))
+		return checkFloat(strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64))
+	case 0xcb:
+		u, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return checkFloat(math.Float64frombits(u), nil)
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if u > math.MaxInt64 {
+			return u, nil
+		}
+		return int64(u), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (c - 0xd0)
+		u, err := d.uint(n)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*n
+		return int64(u<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1<<(c-0xd4), start)
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayN(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapN(int(n))
+	default:
+		return nil, fmt.Errorf("invalid type byte %#02x at offset %d", c, start)
+	}
+}
+
+// checkFloat rejects the NaN and infinities that JSON cannot hold.
+func checkFloat(f float64, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("unsupported float %v", f)
+	}
+	return f, nil
+}
+
+func (d *decoder) str(n int) (any, error) {
+	start := d.off
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(b) {
+		return nil, fmt.Errorf("invalid UTF-8 in string at offset %d", start)
+	}
+	return string(b), nil
+}
+
+func (d *decoder) enter() error {
+	if d.opts.MaxDepth > 0 && d.depth >= d.opts.MaxDepth {
+		return fmt.Errorf("maximum depth exceeded at offset %d", d.off-1)
+	}
+	d.depth++
+	return nil
+}
+
+func (d *decoder) arrayN(n int) (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	// Every element takes at least one byte, which bounds the allocation.
+	if n > len(d.data)-d.off {
+		return nil, fmt.Errorf("unexpected end of input at offset %d", len(d.data))
+	}
+	arr := make([]any, n)
+	for i := range arr {
+		val, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = val
+	}
+	return arr, nil
+}
+
+func (d *decoder) mapN(n int) (any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { d.depth-- }()
+
+	if n > (len(d.data)-d.off)/2 {
+		return nil, fmt.Errorf("unexpected end of input at offset %d", len(d.data))
+	}
+	obj := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		keyStart := d.off
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		switch k := k.(type) {
+		case string:
+			key = k
+		case int64:
+			key = strconv.FormatInt(k, 10)
+		case uint64:
+			key = strconv.FormatUint(k, 10)
+		default:
+			return nil, fmt.Errorf("unsupported map key type %T at offset %d", k, keyStart)
+		}
+		if _, ok := obj[key]; ok {
+			return nil, fmt.Errorf("duplicate key %s at offset %d", key, keyStart)
+		}
+		val, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = val
+	}
+	return obj, nil
+}
+
+func (d *decoder) ext(n int, start int) (any, error) {
+	t, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	typ := int8(t[0])
+	data, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ == timestampExt {
+		ts, err := timestamp(data)
+		if err != nil {
+			return nil, fmt.Errorf("%v at offset %d", err, start)
+		}
+		return ts.UTC().Format(time.RFC3339Nano), nil
+	}
+	if d.opts.Ext == nil {
+		return nil, fmt.Errorf("unsupported extension type %d at offset %d", typ, start)
+	}
+	val, err := d.opts.Ext(typ, append([]byte(nil), data...))
+	if err != nil {
+		return nil, err
+	}
+	return jchain.From(val).Any()
+}
+
+func timestamp(b []byte) (time.Time, error) {
+	switch len(b) {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0), nil
+	case 8:
+		u := binary.BigEndian.Uint64(b)
+		if u>>34 > 999999999 {
+			return time.Time{}, fmt.Errorf("invalid timestamp nanoseconds %d", u>>34)
+		}
+		return time.Unix(int64(u&(1<<34-1)), int64(u>>34)), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(b)
+		if nsec > 999999999 {
+			return time.Time{}, fmt.Errorf("invalid timestamp nanoseconds %d", nsec)
+		}
+		sec := int64(binary.BigEndian.Uint64(b[4:]))
+		return time.Unix(sec, int64(nsec)), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp length %d", len(b))
+	}
+}
+
+// Encode writes v as MessagePack. Map keys are written in sorted order so
+// equal values encode to equal bytes. Strings are always written as str,
+// including those that were decoded from bin or timestamp data, and no ext
+// is ever written. Integers beyond 64 bits have no MessagePack form and are an
+// error.
+func Encode(v *jchain.Value) ([]byte, error) {
+	data, err := v.Any()
+	if err != nil {
+		return nil, err
+	}
+	return appendValue(nil, data)
+}
+
+func appendValue(buf []byte, val any) ([]byte, error) {
+	switch val := val.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if val {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case int:
+		return appendInt(buf, int64(val)), nil
+	case int64:
+		return appendInt(buf, val), nil
+	case uint64:
+		return appendUint(buf, val), nil
+	case *big.Int:
+		return nil, fmt.Errorf("integer %v does not fit in 64 bits", val)
+	case float64:
+		buf = append(buf, 0xcb)
+		return appendUint64(buf, math.Float64bits(val)), nil
+	case string:
+		return appendStr(buf, val), nil
+	case []any:
+		buf = appendHeader(buf, len(val), 0x90, 0xdc)
+		for _, elem := range val {
+			var err error
+			if buf, err = appendValue(buf, elem); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf = appendHeader(buf, len(val), 0x80, 0xde)
+		for _, k := range keys {
+			buf = appendStr(buf, k)
+			var err error
+			if buf, err = appendValue(buf, val[k]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("invalid value %T", val)
+	}
+}
+
+func appendInt(buf []byte, n int64) []byte {
+	switch {
+	case n >= 0:
+		return appendUint(buf, uint64(n))
+	case n >= -32:
+		return append(buf, byte(n))
+	case n >= math.MinInt8:
+		return append(buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return appendUint16(append(buf, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return appendUint32(append(buf, 0xd2), uint32(n))
+	default:
+		return appendUint64(append(buf, 0xd3), uint64(n))
+	}
+}
+
+func appendUint(buf []byte, n uint64) []byte {
+	switch {
+	case n <= 0x7f:
+		return append(buf, byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(buf, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		return appendUint32(append(buf, 0xce), uint32(n))
+	default:
+		return appendUint64(append(buf, 0xcf), n)
+	}
+}
+
+func appendStr(buf []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n <= 31:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = appendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = appendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+// appendHeader writes the length of a map or array: fix is the fixmap or
+// fixarray prefix and wide the 16-bit form, followed by the 32-bit form.
+func appendHeader(buf []byte, n int, fix, wide byte) []byte {
+	switch {
+	case n <= 15:
+		return append(buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(buf, wide), uint16(n))
+	default:
+		return appendUint32(append(buf, wide+1), uint32(n))
+	}
+}
+
+func appendUint16(buf []byte, n uint16) []byte {
+	return append(buf, byte(n>>8), byte(n))
+}
+
+func appendUint32(buf []byte, n uint32) []byte {
+	return append(buf, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendUint64(buf []byte, n uint64) []byte {
+	return appendUint32(appendUint32(buf, uint32(n>>32)), uint32(n))
+}
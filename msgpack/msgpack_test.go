@@ -0,0 +1,195 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mntwlds/jchain"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		hex, json string
+	}{
+		{"c0", `null`},
+		{"c3", `true`},
+		{"7f", `127`},
+		{"e0", `-32`},
+		{"cc ff", `255`},
+		{"cd 01 00", `256`},
+		{"cf ff ff ff ff ff ff ff ff", `18446744073709551615`},
+		{"d0 80", `-128`},
+		{"d1 ff 00", `-256`},
+		{"d3 80 00 00 00 00 00 00 00", `-9223372036854775808`},
+		{"ca 3d cc cc cd", `0.1`},
+		{"cb 3f f8 00 00 00 00 00 00", `1.5`},
+		{"a3 61 62 63", `"abc"`},
+		{"d9 03 78 79 7a", `"xyz"`},
+		{"c4 02 68 69", `"aGk="`},
+		{"92 01 a1 61", `[1, "a"]`},
+		{"82 a1 61 01 a1 62 90", `{"a": 1, "b": []}`},
+		{"81 07 c2", `{"7": false}`},
+		{"d6 ff 65 54 15 00", `"2023-11-15T00:46:56Z"`},
+		{"d7 ff 00 00 00 04 65 54 15 00", `"2023-11-15T00:46:56.000000001Z"`},
+		{"c7 0c ff 00 00 00 02 ff ff ff ff ff ff ff ff", `"1969-12-31T23:59:59.000000002Z"`},
+	}
+	for _, tt := range tests {
+		got := Decode(unhex(t, tt.hex))
+		if err := got.Error(); err != nil {
+			t.Errorf("%s: %v", tt.hex, err)
+			continue
+		}
+		if !got.Equal(jchain.Parse(tt.json)) {
+			t.Errorf("%s: got %v, want %s", tt.hex, got, tt.json)
+		}
+	}
+
+	if k := Decode(unhex(t, "cb 40 00 00 00 00 00 00 00")).Kind(); k != jchain.Float {
+		t.Errorf("expected float64 2.0 to stay a float, got %v", k)
+	}
+	if k := Decode(unhex(t, "cc 05")).Kind(); k != jchain.Int {
+		t.Errorf("expected uint8 to be an int, got %v", k)
+	}
+	b, err := Decode(unhex(t, "c4 02 68 69")).Bytes()
+	if err != nil || string(b) != "hi" {
+		t.Errorf("expected binary to read back with Bytes, got %q, %v", b, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                     "unexpected end of input at offset 0",
+		"c1":                   "invalid type byte 0xc1 at offset 0",
+		"a3 61":                "unexpected end of input at offset 2",
+		"dd ff ff ff ff":       "unexpected end of input at offset 5",
+		"c0 c0":                "unexpected data after value at offset 1",
+		"82 a1 61 01 a1 62":    "unexpected end of input at offset 6",
+		"82 a1 61 01 a1 61 02": "duplicate key a at offset 4",
+		"81 c0 01":             "unsupported map key type <nil> at offset 1",
+		"a1 ff":                "invalid UTF-8 in string at offset 1",
+		"d4 05 00":             "unsupported extension type 5 at offset 0",
+		"d5 ff 00 00":          "invalid timestamp length 2 at offset 0",
+		"91 91 91 c0":          "maximum depth exceeded at offset 2",
+	}
+	for in, msg := range tests {
+		err := DecodeWithOptions(unhex(t, in), DecodeOptions{MaxDepth: 2}).Error()
+		if err == nil || err.Error() != msg {
+			t.Errorf("%q: expected %q, got %v", in, msg, err)
+		}
+	}
+}
+
+func TestDecodeExt(t *testing.T) {
+	opts := DecodeOptions{Ext: func(typ int8, data []byte) (any, error) {
+		if typ != 5 {
+			return nil, fmt.Errorf("unknown type %d", typ)
+		}
+		return map[string]any{"ext": typ, "len": len(data)}, nil
+	}}
+	got := DecodeWithOptions(unhex(t, "91 d5 05 aa bb"), opts)
+	if !got.Equal(jchain.Parse(`[{"ext": 5, "len": 2}]`)) {
+		t.Errorf("got %v, %v", got, got.Error())
+	}
+	if err := DecodeWithOptions(unhex(t, "d4 06 00"), opts).Error(); err == nil || err.Error() != "unknown type 6" {
+		t.Errorf("expected handler error, got %v", err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		json, hex string
+	}{
+		{`null`, "c0"},
+		{`false`, "c2"},
+		{`5`, "05"},
+		{`-1`, "ff"},
+		{`-33`, "d0 df"},
+		{`200`, "cc c8"},
+		{`-40000`, "d2 ff ff 63 c0"},
+		{`18446744073709551615`, "cf ff ff ff ff ff ff ff ff"},
+		{`1.0`, "cb 3f f0 00 00 00 00 00 00"},
+		{`"é"`, "a2 c3 a9"},
+		{`{"b": [1], "a": {}}`, "82 a1 61 80 a1 62 91 01"},
+	}
+	for _, tt := range tests {
+		got, err := Encode(jchain.Parse(tt.json))
+		if err != nil {
+			t.Errorf("%s: %v", tt.json, err)
+			continue
+		}
+		if want := unhex(t, tt.hex); !bytes.Equal(got, want) {
+			t.Errorf("%s: got % x, want % x", tt.json, got, want)
+		}
+	}
+
+	long := jchain.Parse(`{"s": "` + strings.Repeat("x", 300) + `", "list": [` + strings.TrimSuffix(strings.Repeat("0,", 20), ",") + `]}`)
+	data, err := Encode(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back := Decode(data); !back.Equal(long) {
+		t.Errorf("round trip mismatch: %v", back.Error())
+	}
+
+	// bin, timestamp and ext values come back as the str and map they
+	// decoded to.
+	lossy := []struct{ in, out string }{
+		{"c4 02 68 69", "a4 61 47 6b 3d"},
+		{"d6 ff 65 54 15 00", "b4 32 30 32 33 2d 31 31 2d 31 35 54 30 30 3a 34 36 3a 35 36 5a"},
+	}
+	for _, tt := range lossy {
+		got, err := Encode(Decode(unhex(t, tt.in)))
+		if want := unhex(t, tt.out); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s: got % x, %v, want % x", tt.in, got, err, want)
+		}
+	}
+	ext := DecodeWithOptions(unhex(t, "d4 05 01"), DecodeOptions{Ext: func(typ int8, data []byte) (any, error) {
+		return int(data[0]), nil
+	}})
+	if got, err := Encode(ext); err != nil || !bytes.Equal(got, []byte{0x01}) {
+		t.Errorf("ext: got % x, %v", got, err)
+	}
+
+	big := jchain.ParseWithOptions(`18446744073709551616`, jchain.ParseOptions{BigNumbers: true})
+	if _, err := Encode(big); err == nil || err.Error() != "integer 18446744073709551616 does not fit in 64 bits" {
+		t.Errorf("expected an error for a big integer, got %v", err)
+	}
+	if _, err := Encode(jchain.Parse(`{`)); err == nil {
+		t.Error("expected the chain error to be returned")
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	nested := func(n int) string {
+		return strings.Repeat("[", n) + strings.Repeat("]", n)
+	}
+	v := jchain.Parse(nested(1000))
+	data, err := Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back := Decode(data); !back.Equal(v) {
+		t.Errorf("round trip of 1000 levels gave %v", back.Error())
+	}
+	deeper := append(bytes.Repeat([]byte{0x91}, 1000), 0x90)
+	if err := Decode(deeper).Error(); err == nil || err.Error() != "maximum depth exceeded at offset 1000" {
+		t.Errorf("expected Decode to stop at 1000 levels, got %v", err)
+	}
+	for _, max := range []int{0, 5000} {
+		if err := DecodeWithOptions(deeper, DecodeOptions{MaxDepth: max}).Error(); err != nil {
+			t.Errorf("MaxDepth %d: %v", max, err)
+		}
+	}
+}
@@ -2,31 +2,68 @@ package jchain
 
 import (
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
-	"unicode/utf16"
 	"unicode/utf8"
 )
 
-type parser struct {
-	input    string
+// jsonText is the input of a parser. Parsing []byte directly saves copying
+// it to a string first.
+type jsonText interface {
+	~string | ~[]byte
+}
+
+type parser[T jsonText] struct {
+	input    T
 	len      int
 	maxDepth int
 	depth    int
+	relaxed  bool
+	big      bool
+
+	// When recovering, syntax errors are collected in errs instead of
+	// aborting the parse.
+	recovering bool
+	errs       []*SyntaxError
+	src        *source
+
+	// When track is set every parsed value records its span, building a
+	// tree rooted at root. cur is the container currently being parsed.
+	track bool
+	cur   *span
+	root  *span
+}
+
+// span is the byte range [start, end) of a value in the input. Object members
+// also record the range of their key, including quotes.
+type span struct {
+	start, end       int
+	key              string
+	keyStart, keyEnd int
+	children         []*span
+	incomplete       bool
 }
 
-func parseJSON(jsonStr string, maxDepth int) (res any, err error) {
-	p := &parser{
+func newParser[T jsonText](jsonStr T, opts ParseOptions) *parser[T] {
+	return &parser[T]{
 		input:    jsonStr,
 		len:      len(jsonStr),
-		maxDepth: maxDepth,
+		maxDepth: opts.MaxDepth,
+		relaxed:  opts.Relaxed,
+		big:      opts.BigNumbers,
 	}
+}
+
+func parseJSON[T jsonText](jsonStr T, maxDepth int) (any, error) {
+	return newParser(jsonStr, ParseOptions{MaxDepth: maxDepth}).parse()
+}
 
+func (p *parser[T]) parse() (res any, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if pErr, ok := r.(parserError); ok {
-				line, col := p.calculateLineCol(pErr.pos)
-				err = fmt.Errorf("%s at line %d, column %d", pErr.msg, line, col)
+				err = p.report(pErr)
 			} else {
 				err = fmt.Errorf("%v", r)
 			}
@@ -35,46 +72,247 @@ func parseJSON(jsonStr string, maxDepth int) (res any, err error) {
 	}()
 
 	i := p.skipWhitespace(0)
-	value, i := p.parseValue(i)
+	value, i := p.parseElement(i)
 	i = p.skipWhitespace(i)
 
 	if !p.checkOOB(i) {
-		p.error(i, "Invalid JSON")
+		p.fail(i, -1, "unexpected data after top-level value")
 	}
 
+	if synErr, ok := value.(*SyntaxError); ok {
+		return nil, synErr
+	}
 	return value, nil
 }
 
-func (p *parser) error(pos int, msg string) {
-	panic(parserError{pos: pos, msg: msg})
+func (p *parser[T]) source() *source {
+	if p.src == nil {
+		p.src = newSource("", string(p.input))
+	}
+	return p.src
+}
+
+// report records a syntax error, skipping repeats at the same offset, which
+// happen when every enclosing container hits the end of the input.
+func (p *parser[T]) report(pErr parserError) *SyntaxError {
+	if n := len(p.errs); n > 0 && p.errs[n-1].Offset == pErr.pos {
+		return p.errs[n-1]
+	}
+	synErr := newSyntaxError(p.source(), string(p.input), pErr)
+	p.errs = append(p.errs, synErr)
+	return synErr
+}
+
+// fail reports an error at pos. Unless the parser is recovering it does not
+// return.
+func (p *parser[T]) fail(pos int, opened int, msg string) {
+	if !p.recovering {
+		p.errorOpened(pos, opened, msg)
+	}
+	p.report(parserError{pos: pos, msg: msg, opened: opened})
+}
+
+// parseElement parses a value inside a container. When recovering, a syntax
+// error in the value is recorded, the value is replaced by the error as a
+// placeholder, and parsing resumes at the next separator.
+func (p *parser[T]) parseElement(i int) (val any, end int) {
+	if !p.recovering {
+		return p.parseValue(i)
+	}
+
+	cur := p.cur
+	defer func() {
+		if r := recover(); r != nil {
+			pErr, ok := r.(parserError)
+			if !ok {
+				panic(r)
+			}
+			p.cur = cur
+			val, end = p.report(pErr), p.resync(i)
+			if p.track {
+				node := &span{start: i, end: end}
+				if cur != nil {
+					cur.children = append(cur.children, node)
+				} else {
+					p.root = node
+				}
+			}
+		}
+	}()
+	return p.parseValue(i)
+}
+
+// hole marks where ParseRecover left a placeholder, or the members and
+// items below which it left some.
+type hole struct {
+	err     *SyntaxError
+	members map[string]*hole
+	items   map[int]*hole
+}
+
+func (h *hole) member(key string) *hole {
+	if h == nil {
+		return nil
+	}
+	return h.members[key]
+}
+
+func (h *hole) item(i int) *hole {
+	if h == nil {
+		return nil
+	}
+	return h.items[i]
+}
+
+// takeHoles replaces the placeholders in val with null, in place, and
+// returns where they were.
+func takeHoles(val any) (any, *hole) {
+	switch val := val.(type) {
+	case *SyntaxError:
+		return nil, &hole{err: val}
+	case map[string]any:
+		var h *hole
+		for key, elem := range val {
+			if elem, sub := takeHoles(elem); sub != nil {
+				val[key] = elem
+				if h == nil {
+					h = &hole{members: map[string]*hole{}}
+				}
+				h.members[key] = sub
+			}
+		}
+		return val, h
+	case []any:
+		var h *hole
+		for i, elem := range val {
+			if elem, sub := takeHoles(elem); sub != nil {
+				val[i] = elem
+				if h == nil {
+					h = &hole{items: map[int]*hole{}}
+				}
+				h.items[i] = sub
+			}
+		}
+		return val, h
+	}
+	return val, nil
+}
+
+func (p *parser[T]) parseKey(i int) (key string, end int, err *SyntaxError) {
+	if !p.recovering {
+		key, end = p.parseString(i)
+		return key, end, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			pErr, ok := r.(parserError)
+			if !ok {
+				panic(r)
+			}
+			err = p.report(pErr)
+		}
+	}()
+	key, end = p.parseString(i)
+	return key, end, nil
+}
+
+// resync skips from i to the next ',' or closing bracket outside of nested
+// containers and strings.
+func (p *parser[T]) resync(i int) int {
+	depth := 0
+	for ; i < p.len; i++ {
+		switch p.input[i] {
+		case '"':
+			for i++; i < p.len && p.input[i] != '"'; i++ {
+				if p.input[i] == '\\' {
+					i++
+				}
+			}
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// resume continues a container after an error at i by skipping to its next
+// member. It reports whether the container has ended, which is also the case
+// when a different container's closing bracket is found.
+func (p *parser[T]) resume(i int, closer byte) (int, bool) {
+	i = p.resync(i)
+	if i < p.len && p.input[i] == ',' {
+		i = p.skipWhitespace(i + 1)
+		if i < p.len && p.input[i] == closer && p.relaxed {
+			return i + 1, true
+		}
+		return i, false
+	}
+	if i < p.len && p.input[i] == closer {
+		return i + 1, true
+	}
+	return i, true
+}
+
+func startsValue(c byte) bool {
+	switch c {
+	case '{', '[', '"', '-', 't', 'f', 'n':
+		return true
+	}
+	return '0' <= c && c <= '9'
+}
+
+func (p *parser[T]) error(pos int, msg string) {
+	panic(parserError{pos: pos, msg: msg, opened: -1})
+}
+
+// errorOpened reports an error inside the object, array or string that
+// starts at opened.
+func (p *parser[T]) errorOpened(pos int, opened int, msg string) {
+	panic(parserError{pos: pos, msg: msg, opened: opened})
 }
 
 type parserError struct {
-	pos int
-	msg string
+	pos    int
+	msg    string
+	opened int
 }
 
-func (p *parser) checkOOB(i int) bool {
+func (p *parser[T]) checkOOB(i int) bool {
 	return i >= p.len
 }
 
-func (p *parser) calculateLineCol(pos int) (int, int) {
-	line := 1
-	col := 1
-	for i := 0; i < pos && i < p.len; i++ {
-		if p.input[i] == '\n' {
-			line++
-			col = 1
-		} else {
-			col++
-		}
+func (p *parser[T]) parseValue(i int) (any, int) {
+	if !p.track {
+		return p.parseToken(i)
+	}
+
+	node := &span{start: i}
+	parent := p.cur
+	p.cur = node
+	val, end := p.parseToken(i)
+	p.cur = parent
+	node.end = end
+	if parent != nil {
+		parent.children = append(parent.children, node)
+	} else {
+		p.root = node
 	}
-	return line, col
+	return val, end
 }
 
-func (p *parser) parseValue(i int) (any, int) {
+func (p *parser[T]) parseToken(i int) (any, int) {
 	if p.checkOOB(i) {
-		p.error(i, "Invalid JSON")
+		p.error(i, "expected value")
 	}
 
 	switch p.input[i] {
@@ -86,299 +324,323 @@ func (p *parser) parseValue(i int) (any, int) {
 		return p.parseString(i)
 	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		return p.parseNumber(i)
-	case 't':
-		if i+4 > p.len || p.input[i:i+4] != "true" {
-			p.error(i, "Invalid JSON")
+	case 't', 'f', 'n':
+		lit := literalText(p.input[i])
+		if i+len(lit) > p.len || string(p.input[i:i+len(lit)]) != lit {
+			p.error(i, "invalid literal, expected '"+lit+"'")
 		}
-		return true, i + 4
-	case 'f':
-		if i+5 > p.len || p.input[i:i+5] != "false" {
-			p.error(i, "Invalid JSON")
+		if lit == "null" {
+			return nil, i + len(lit)
 		}
-		return false, i + 5
-	case 'n':
-		if i+4 > p.len || p.input[i:i+4] != "null" {
-			p.error(i, "Invalid JSON")
-		}
-		return nil, i + 4
+		return lit == "true", i + len(lit)
 	default:
-		p.error(i, "Invalid JSON")
+		p.error(i, "expected value")
 	}
 	return nil, i // Should be unreachable
 }
 
-func (p *parser) skipWhitespace(i int) int {
-	for i < p.len && (p.input[i] == ' ' || p.input[i] == '\t' || p.input[i] == '\n' || p.input[i] == '\r') {
-		i++
+func (p *parser[T]) skipWhitespace(i int) int {
+	for i < p.len {
+		switch p.input[i] {
+		case ' ', '\t', '\n', '\r':
+			i++
+		case '/':
+			if !p.relaxed || i+1 >= p.len {
+				return i
+			}
+			switch p.input[i+1] {
+			case '/':
+				for i < p.len && p.input[i] != '\n' {
+					i++
+				}
+			case '*':
+				end := i + 2
+				for end+1 < p.len && (p.input[end] != '*' || p.input[end+1] != '/') {
+					end++
+				}
+				if end+1 >= p.len {
+					p.error(i, "unterminated comment")
+				}
+				i = end + 2
+			default:
+				return i
+			}
+		default:
+			return i
+		}
 	}
 	return i
 }
 
-func (p *parser) parseObject(i int) (map[string]any, int) {
+func (p *parser[T]) parseObject(i int) (map[string]any, int) {
 	if p.maxDepth > 0 && p.depth >= p.maxDepth {
-		p.error(i, "Maximum depth exceeded")
+		p.error(i, "maximum depth exceeded")
 	}
 	p.depth++
 	defer func() { p.depth-- }()
 
 	if i >= p.len || p.input[i] != '{' {
-		p.error(i, "Invalid JSON")
+		p.error(i, "expected '{'")
 	}
+	open := i
 	i++
 	jsonMap := make(map[string]any)
 	i = p.skipWhitespace(i)
-	if i < p.len && p.input[i] != '}' {
-		for {
-			var key string
-			key, i = p.parseString(i)
-			if _, ok := jsonMap[key]; ok {
-				p.error(i, "Duplicate key "+key)
+	if i < p.len && p.input[i] == '}' {
+		return jsonMap, i + 1
+	}
+
+	var done bool
+	for {
+		if i >= p.len || p.input[i] != '"' {
+			if len(jsonMap) == 0 {
+				p.fail(i, open, "expected string key or '}'")
+			} else {
+				p.fail(i, open, "expected string key")
 			}
-			i = p.skipWhitespace(i)
-			if i >= p.len || p.input[i] != ':' {
-				p.error(i, "Invalid JSON")
+			if i, done = p.resume(i, '}'); done {
+				return jsonMap, i
 			}
-			i++
-			i = p.skipWhitespace(i)
-			var value any
-			value, i = p.parseValue(i)
-			i = p.skipWhitespace(i)
-			jsonMap[key] = value
-			if i >= p.len {
-				p.error(i, "Invalid JSON")
+			continue
+		}
+
+		keyStart := i
+		var key string
+		var err *SyntaxError
+		key, i, err = p.parseKey(i)
+		if err != nil {
+			if i, done = p.resume(keyStart, '}'); done {
+				return jsonMap, i
 			}
-			if p.input[i] == ',' {
-				i++
-				i = p.skipWhitespace(i)
-			} else if p.input[i] == '}' {
-				i++
-				break
-			} else {
-				p.error(i, "Invalid JSON")
+			continue
+		}
+		keyEnd := i
+		_, dup := jsonMap[key]
+		if dup {
+			p.fail(keyStart, -1, "duplicate key "+key)
+		}
+
+		i = p.skipWhitespace(i)
+		if i < p.len && p.input[i] == ':' {
+			i = p.skipWhitespace(i + 1)
+		} else {
+			p.fail(i, open, "expected ':' after object key")
+			// Carry on as if the colon was there when a value follows.
+			if i >= p.len || !startsValue(p.input[i]) {
+				if !dup {
+					jsonMap[key] = p.errs[len(p.errs)-1]
+				}
+				if i, done = p.resume(i, '}'); done {
+					return jsonMap, i
+				}
+				continue
 			}
 		}
-	} else if i < p.len && p.input[i] == '}' {
-		i++
-	} else {
-		p.error(i, "Invalid JSON")
+		if i >= p.len {
+			p.fail(i, open, "expected value")
+			return jsonMap, i
+		}
+
+		var value any
+		value, i = p.parseElement(i)
+		if !dup {
+			jsonMap[key] = value
+		}
+		if p.track {
+			member := p.cur.children[len(p.cur.children)-1]
+			member.key, member.keyStart, member.keyEnd = key, keyStart, keyEnd
+		}
+
+		i = p.skipWhitespace(i)
+		if i < p.len && p.input[i] == ',' {
+			i = p.skipWhitespace(i + 1)
+			if p.relaxed && i < p.len && p.input[i] == '}' {
+				return jsonMap, i + 1
+			}
+			continue
+		}
+		if i < p.len && p.input[i] == '}' {
+			return jsonMap, i + 1
+		}
+		p.fail(i, open, "expected ',' or '}' after object member")
+		if i < p.len && p.input[i] == '"' {
+			continue
+		}
+		if i, done = p.resume(i, '}'); done {
+			return jsonMap, i
+		}
 	}
-	return jsonMap, i
 }
 
-func (p *parser) parseArray(i int) ([]any, int) {
+func (p *parser[T]) parseArray(i int) ([]any, int) {
 	if p.maxDepth > 0 && p.depth >= p.maxDepth {
-		p.error(i, "Maximum depth exceeded")
+		p.error(i, "maximum depth exceeded")
 	}
 	p.depth++
 	defer func() { p.depth-- }()
 
 	if i >= p.len || p.input[i] != '[' {
-		p.error(i, "Invalid JSON")
+		p.error(i, "expected '['")
 	}
+	open := i
 	i++
 	jsonArray := make([]any, 0)
 	i = p.skipWhitespace(i)
-	if i < p.len && p.input[i] != ']' {
-		for {
-			var value any
-			value, i = p.parseValue(i)
-			i = p.skipWhitespace(i)
-			jsonArray = append(jsonArray, value)
-			if i >= p.len {
-				p.error(i, "Invalid JSON")
-			}
-			if p.input[i] == ',' {
-				i++
-				i = p.skipWhitespace(i)
-			} else if p.input[i] == ']' {
-				i++
-				break
+	if i < p.len && p.input[i] == ']' {
+		return jsonArray, i + 1
+	}
+
+	var done bool
+	for {
+		if i >= p.len {
+			if len(jsonArray) == 0 {
+				p.fail(i, open, "expected value or ']'")
 			} else {
-				p.error(i, "Invalid JSON")
+				p.fail(i, open, "expected value")
 			}
+			return jsonArray, i
+		}
+
+		var value any
+		value, i = p.parseElement(i)
+		jsonArray = append(jsonArray, value)
+
+		i = p.skipWhitespace(i)
+		if i < p.len && p.input[i] == ',' {
+			i = p.skipWhitespace(i + 1)
+			if (p.relaxed || p.recovering) && i < p.len && p.input[i] == ']' {
+				// When recovering, a trailing comma is reported but leaves
+				// no placeholder behind.
+				if !p.relaxed {
+					p.fail(i, open, "expected value")
+				}
+				return jsonArray, i + 1
+			}
+			continue
+		}
+		if i < p.len && p.input[i] == ']' {
+			return jsonArray, i + 1
+		}
+		p.fail(i, open, "expected ',' or ']' after array element")
+		// Carry on as if the comma was there when a value follows.
+		if i < p.len && startsValue(p.input[i]) {
+			continue
+		}
+		if i, done = p.resume(i, ']'); done {
+			return jsonArray, i
 		}
-	} else if i < p.len && p.input[i] == ']' {
-		i++
-	} else {
-		p.error(i, "Invalid JSON")
 	}
-	return jsonArray, i
 }
 
-func (p *parser) parseString(i int) (string, int) {
+func (p *parser[T]) parseString(i int) (string, int) {
 	if i >= p.len || p.input[i] != '"' {
-		p.error(i, "Invalid JSON")
+		p.error(i, "expected string")
 	}
+	open := i
 	i++
 	var sb strings.Builder
-	if i >= p.len || p.input[i] != '"' {
-		for i < p.len {
-			if p.input[i] == '\\' {
-				i++
-				switch p.input[i] {
-				case '"':
-					sb.WriteByte('"')
-					i++
-				case '\\':
-					sb.WriteByte('\\')
-					i++
-				case '/':
-					sb.WriteByte('/')
-					i++
-				case 'b':
-					sb.WriteByte('\b')
-					i++
-				case 'f':
-					sb.WriteByte('\f')
-					i++
-				case 'n':
-					sb.WriteByte('\n')
-					i++
-				case 'r':
-					sb.WriteByte('\r')
-					i++
-				case 't':
-					sb.WriteByte('\t')
-					i++
-				case 'u':
-					i++
-					if i+4 > p.len {
-						p.error(i, "Invalid JSON")
-					}
-					val, err := strconv.ParseInt(p.input[i:i+4], 16, 32)
-					if err != nil {
-						p.error(i, "Invalid JSON")
-					}
-					if 0xD800 <= val && val <= 0xDBFF {
-						i += 4
-						if i+6 > p.len || p.input[i:i+2] != "\\u" {
-							p.error(i, "Invalid JSON")
-						}
-						i += 2
-						val2, err := strconv.ParseInt(p.input[i:i+4], 16, 32)
-						if err != nil {
-							p.error(i, "Invalid JSON")
-						}
-						if 0xDC00 <= val2 && val2 <= 0xDFFF {
-							sb.WriteRune(utf16.DecodeRune(rune(val), rune(val2)))
-							i += 4
-						} else {
-							p.error(i, "Invalid JSON")
-						}
-					} else if 0xDC00 <= val && val <= 0xDFFF {
-						p.error(i, "Invalid JSON")
-					} else {
-						sb.WriteRune(rune(val))
-						i += 4
-					}
-				default:
-					p.error(i, "Invalid JSON")
-				}
-			} else if p.input[i] == '"' {
-				break
-			} else {
-				val, size := utf8.DecodeRuneInString(p.input[i:])
-				if val == utf8.RuneError && size == 1 {
-					p.error(i, "Invalid JSON")
+	for i < p.len {
+		switch c := p.input[i]; {
+		case c == '"':
+			return sb.String(), i + 1
+		case c == '\\':
+			i = p.parseEscape(i, open, &sb)
+		default:
+			r, size := rune(c), 1
+			if c >= utf8.RuneSelf {
+				end := i + utf8.UTFMax
+				if end > p.len {
+					end = p.len
 				}
-				if val < 0x20 {
-					p.error(i, "Invalid JSON")
-				}
-				sb.WriteRune(val)
-				i += size
+				r, size = utf8.DecodeRuneInString(string(p.input[i:end]))
+			}
+			if msg := stringRuneError(r, size); msg != "" {
+				p.error(i, msg)
 			}
+			sb.WriteRune(r)
+			i += size
 		}
 	}
-	return sb.String(), i + 1
+	p.errorOpened(i, open, "unterminated string")
+	return "", i // Should be unreachable
 }
 
-func (p *parser) parseNumber(i int) (any, int) {
-	if p.checkOOB(i) {
-		p.error(i, "Invalid JSON")
-	}
+// parseEscape decodes the escape sequence at i into sb and returns its end.
+func (p *parser[T]) parseEscape(i int, open int, sb *strings.Builder) int {
 	start := i
-	isInt := true
-	if p.input[i] == '-' {
-		i++
-	}
-	if p.checkOOB(i) {
-		p.error(i, "Invalid JSON")
-	}
-	if p.input[i] == '0' {
+	var esc escapeScanner
+	for {
 		i++
-	} else {
-		if p.input[i] >= '1' && p.input[i] <= '9' {
-			i++
-			if !p.checkOOB(i) {
-				for i < p.len && p.input[i] >= '0' && p.input[i] <= '9' {
-					i++
-				}
+		if i >= p.len {
+			if msg := esc.eofMessage(); msg != "" {
+				p.error(i, msg)
 			}
-		} else {
-			p.error(i, "Invalid JSON")
-		}
-	}
-	if !p.checkOOB(i) && p.input[i] == '.' {
-		isInt = false
-		i++
-		if p.checkOOB(i) {
-			p.error(i, "Invalid JSON")
+			p.errorOpened(i, open, "unterminated string")
 		}
-		if p.input[i] < '0' || p.input[i] > '9' {
-			p.error(i, "Invalid JSON")
+		done, err := esc.step(p.input[i])
+		if err != nil {
+			if err.atStart {
+				i = start
+			}
+			p.error(i, err.msg)
 		}
-		for i < p.len && p.input[i] >= '0' && p.input[i] <= '9' {
-			i++
+		if done {
+			sb.WriteRune(esc.r)
+			return i + 1
 		}
 	}
-	if !p.checkOOB(i) && (p.input[i] == 'e' || p.input[i] == 'E') {
-		isInt = false
+}
+
+func (p *parser[T]) parseNumber(i int) (any, int) {
+	start := i
+	var num numberScanner
+	for i < p.len && num.step(p.input[i]) {
 		i++
-		if p.checkOOB(i) {
-			p.error(i, "Invalid JSON")
-		}
-		if p.input[i] == '+' || p.input[i] == '-' {
-			i++
-		}
-		if p.checkOOB(i) {
-			p.error(i, "Invalid JSON")
-		}
-		if p.input[i] < '0' || p.input[i] > '9' {
-			p.error(i, "Invalid JSON")
-		}
-		for i < p.len && p.input[i] >= '0' && p.input[i] <= '9' {
-			i++
-		}
+	}
+	if !num.complete() {
+		p.error(i, "invalid number, expected digit")
 	}
 
-	temp := p.input[start:i]
+	val, err := numberValue(string(p.input[start:i]), !num.isFloat, p.big)
+	if err != nil {
+		p.error(start, err.Error())
+	}
+	return val, i
+}
 
+// numberValue converts the text of a valid JSON number. Integers become
+// int64, or uint64 when only that fits, and everything else float64. With
+// exact set, integers beyond both become *big.Int rather than float64.
+func numberValue(raw string, isInt, exact bool) (any, error) {
 	if isInt {
-		number, err := strconv.ParseInt(temp, 10, 64)
+		number, err := strconv.ParseInt(raw, 10, 64)
 		if err != nil {
 			if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
 				// If out of range for int64, check if it fits in uint64 (only for positive numbers)
-				if temp[0] != '-' {
-					uNumber, uErr := strconv.ParseUint(temp, 10, 64)
+				if raw[0] != '-' {
+					uNumber, uErr := strconv.ParseUint(raw, 10, 64)
 					if uErr == nil {
-						return uNumber, i
+						return uNumber, nil
 					}
 				}
 
-				fNumber, fErr := strconv.ParseFloat(temp, 64)
+				if exact {
+					n, _ := new(big.Int).SetString(raw, 10)
+					return n, nil
+				}
+				fNumber, fErr := strconv.ParseFloat(raw, 64)
 				if fErr != nil {
-					p.error(i, "Invalid number format")
+					return nil, fmt.Errorf("number out of range")
 				}
-				return fNumber, i
+				return fNumber, nil
 			}
-			p.error(i, "Invalid JSON")
+			return nil, fmt.Errorf("invalid number")
 		}
-		return number, i
-	} else {
-		number, err := strconv.ParseFloat(temp, 64)
-		if err != nil {
-			p.error(i, "Invalid JSON")
-		}
-		return number, i
+		return number, nil
+	}
+	number, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("number out of range")
 	}
+	return number, nil
 }
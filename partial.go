@@ -0,0 +1,244 @@
+package jchain
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	expectKey = iota
+	expectColon
+	expectValue
+	expectComma
+)
+
+type partialFrame struct {
+	open  byte
+	state int
+	// last is the offset just past the container's last complete member,
+	// or past its opening bracket.
+	last int
+}
+
+// ParsePartial parses a JSON text that may have been cut off, as happens
+// while a document is still being streamed. Open strings, arrays and objects
+// are closed at the cut-off point, object members whose value has not started
+// yet are dropped, and partial literals are completed. Values that may still
+// change as more input arrives report true from Incomplete; input holding no
+// complete token yet, such as "" or "-", gives an incomplete null. Positions
+// refer to the completed text.
+func ParsePartial(json string) *Value {
+	completed, cut := completePartial(json)
+	if strings.Trim(completed, " \t\r\n") == "" {
+		return &Value{kind: Null, span: &span{incomplete: true}, src: newSource("", completed)}
+	}
+
+	p := newParser(completed, ParseOptions{MaxDepth: 1000})
+	p.track = true
+	res, err := p.parse()
+	if err != nil {
+		return &Value{err: err}
+	}
+	// Only a number that ran into the end of the input may still gain
+	// digits; at most a dangling exponent or fraction was dropped after it.
+	numberEnd := -1
+	if strings.Trim(json[cut:], "+-.eE") == "" {
+		numberEnd = cut
+	}
+	markIncomplete(completed, p.root, cut, numberEnd)
+	return &Value{kind: getKind(res), data: res, span: p.root, src: newSource("", completed)}
+}
+
+// Incomplete reports whether the value was still being written when the
+// input of ParsePartial ended.
+func (v *Value) Incomplete() bool {
+	return v.span != nil && v.span.incomplete
+}
+
+func markIncomplete(input string, node *span, cut, numberEnd int) bool {
+	for _, child := range node.children {
+		if markIncomplete(input, child, cut, numberEnd) {
+			node.incomplete = true
+		}
+	}
+	if node.end > cut {
+		node.incomplete = true
+	}
+	if c := input[node.start]; node.end == numberEnd && (c == '-' || ('0' <= c && c <= '9')) {
+		node.incomplete = true
+	}
+	return node.incomplete
+}
+
+// completePartial returns json with whatever is needed appended to make it a
+// complete document, dropping trailing fragments that cannot be completed.
+// cut is the length of the prefix of json that was kept.
+func completePartial(json string) (string, int) {
+	var stack []*partialFrame
+	top := func() *partialFrame {
+		if len(stack) == 0 {
+			return nil
+		}
+		return stack[len(stack)-1]
+	}
+	afterValue := func(i int) {
+		if f := top(); f != nil {
+			f.state, f.last = expectComma, i
+		}
+	}
+	closeAll := func(prefix string) string {
+		var sb strings.Builder
+		sb.WriteString(prefix)
+		for j := len(stack) - 1; j >= 0; j-- {
+			if stack[j].open == '{' {
+				sb.WriteByte('}')
+			} else {
+				sb.WriteByte(']')
+			}
+		}
+		return sb.String()
+	}
+	truncate := func() (string, int) {
+		f := top()
+		if f == nil {
+			return "", 0
+		}
+		return closeAll(json[:f.last]), f.last
+	}
+
+	n := len(json)
+	for i := 0; i < n; {
+		c := json[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '{' || c == '[':
+			f := &partialFrame{open: c, state: expectValue, last: i + 1}
+			if c == '{' {
+				f.state = expectKey
+			}
+			stack = append(stack, f)
+			i++
+		case c == '}' || c == ']':
+			if len(stack) == 0 {
+				return json, n
+			}
+			stack = stack[:len(stack)-1]
+			i++
+			afterValue(i)
+		case c == ':':
+			if f := top(); f != nil {
+				f.state = expectValue
+			}
+			i++
+		case c == ',':
+			if f := top(); f != nil {
+				f.state = expectValue
+				if f.open == '{' {
+					f.state = expectKey
+				}
+			}
+			i++
+		case c == '"':
+			end, closed := scanPartialString(json, i)
+			isKey := top() != nil && top().open == '{' && top().state == expectKey
+			if !closed {
+				if isKey {
+					return truncate()
+				}
+				return closeAll(json[:end] + `"`), end
+			}
+			i = end
+			if isKey {
+				top().state = expectColon
+			} else {
+				afterValue(i)
+			}
+		case c == '-' || ('0' <= c && c <= '9'):
+			end := i
+			for end < n && strings.IndexByte("0123456789+-.eE", json[end]) >= 0 {
+				end++
+			}
+			if end < n {
+				i = end
+				afterValue(i)
+				continue
+			}
+			for end > i && strings.IndexByte("+-.eE", json[end-1]) >= 0 {
+				end--
+			}
+			if end == i {
+				return truncate()
+			}
+			return closeAll(json[:end]), end
+		case 'a' <= c && c <= 'z':
+			end := i
+			for end < n && 'a' <= json[end] && json[end] <= 'z' {
+				end++
+			}
+			if end < n {
+				i = end
+				afterValue(i)
+				continue
+			}
+			for _, lit := range []string{"true", "false", "null"} {
+				if json[i:end] == lit {
+					return closeAll(json), n
+				}
+				if strings.HasPrefix(lit, json[i:end]) {
+					return closeAll(json[:i] + lit), i
+				}
+			}
+			return json, n
+		default:
+			return json, n
+		}
+	}
+
+	f := top()
+	if f == nil || f.state == expectComma {
+		return closeAll(json), n
+	}
+	return truncate()
+}
+
+// scanPartialString returns the offset just past the string starting at i
+// and whether it was closed. For an unclosed string the offset is moved back
+// before any escape sequence, surrogate pair or UTF-8 sequence that was cut
+// in half.
+func scanPartialString(json string, i int) (int, bool) {
+	n := len(json)
+	complete := i + 1
+	for j := i + 1; j < n; {
+		switch c := json[j]; {
+		case c == '"':
+			return j + 1, true
+		case c == '\\':
+			if j+1 >= n {
+				return complete, false
+			}
+			if json[j+1] != 'u' {
+				j += 2
+				complete = j
+				continue
+			}
+			if j+6 > n {
+				return complete, false
+			}
+			// A high surrogate is only complete together with its low half.
+			if h := json[j+2]; (h == 'd' || h == 'D') && strings.IndexByte("89abAB", json[j+3]) >= 0 && j+12 > n {
+				return complete, false
+			}
+			j += 6
+			complete = j
+		default:
+			if !utf8.FullRuneInString(json[j:]) {
+				return complete, false
+			}
+			_, size := utf8.DecodeRuneInString(json[j:])
+			j += size
+			complete = j
+		}
+	}
+	return complete, false
+}
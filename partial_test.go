@@ -0,0 +1,123 @@
+package jchain
+
+import (
+	"testing"
+)
+
+func TestParsePartial(t *testing.T) {
+	tests := []struct {
+		json, want string
+	}{
+		{`{"name": "Al`, `{"name": "Al"}`},
+		{`{"name": "Alice", "tags": ["a", "b`, `{"name": "Alice", "tags": ["a", "b"]}`},
+		{`{"name": "Alice", "ag`, `{"name": "Alice"}`},
+		{`{"name": "Alice", "age":`, `{"name": "Alice"}`},
+		{`{"name": "Alice", "age": 3`, `{"name": "Alice", "age": 3}`},
+		{`{"n": -`, `{}`},
+		{`{"n": 1.`, `{"n": 1}`},
+		{`[1, 2, `, `[1, 2]`},
+		{`[true, fa`, `[true, false]`},
+		{`{"a": {"b": [{"c": nu`, `{"a": {"b": [{"c": null}]}}`},
+		{`["ab\`, `["ab"]`},
+		{`["ab\u00`, `["ab"]`},
+		{`["😀x", "\ud83d`, `["😀x", ""]`},
+		{"[\"\xe2\x82", `[""]`},
+		{`{"done": true}`, `{"done": true}`},
+	}
+	for _, tt := range tests {
+		v := ParsePartial(tt.json)
+		if err := v.Error(); err != nil {
+			t.Errorf("ParsePartial(%s): %v", tt.json, err)
+			continue
+		}
+		if !v.Equal(Parse(tt.want)) {
+			t.Errorf("ParsePartial(%s) = %v, want %s", tt.json, v.data, tt.want)
+		}
+	}
+}
+
+func TestParsePartialIncomplete(t *testing.T) {
+	v := ParsePartial(`{"id": 7, "title": "Hello", "tags": ["x"], "body": "Wor`)
+	if !v.Incomplete() {
+		t.Error("expected root to be incomplete")
+	}
+	for _, key := range []string{"id", "title", "tags"} {
+		if v.Get(key).Incomplete() {
+			t.Errorf("expected %s to be complete", key)
+		}
+	}
+	if !v.Get("body").Incomplete() {
+		t.Error("expected body to be incomplete")
+	}
+
+	v = ParsePartial(`{"count": 12`)
+	if !v.Get("count").Incomplete() {
+		t.Error("expected number at the cut-off to be incomplete")
+	}
+	for _, in := range []string{`{"a": 12, "b": "x`, `{"a": 12, "b`, `{"a": 12, `, `[12, `} {
+		v = ParsePartial(in)
+		num := v.Index(0)
+		if v.Kind() == Object {
+			num = v.Get("a")
+		}
+		if num.Incomplete() {
+			t.Errorf("%s: expected the number followed by a comma to be complete", in)
+		}
+	}
+
+	// A literal that ran into the end is complete once fully written, though
+	// its container is not.
+	for _, in := range []string{`[true`, `{"a": null`, `[false`} {
+		v = ParsePartial(in)
+		lit := v.Index(0)
+		if v.Kind() == Object {
+			lit = v.Get("a")
+		}
+		if err := lit.Error(); err != nil || lit.Incomplete() || !v.Incomplete() {
+			t.Errorf("%s: expected a complete literal in an incomplete container, got %v, %v, %v", in, lit.Incomplete(), v.Incomplete(), err)
+		}
+	}
+	for _, in := range []string{`[tr`, `{"a": nul`} {
+		v = ParsePartial(in)
+		lit := v.Index(0)
+		if v.Kind() == Object {
+			lit = v.Get("a")
+		}
+		if !lit.Incomplete() {
+			t.Errorf("%s: expected the cut literal to be incomplete", in)
+		}
+	}
+
+	v = ParsePartial(`{"ok": true}`)
+	if v.Incomplete() || v.Get("ok").Incomplete() {
+		t.Error("expected complete document")
+	}
+
+	if ParsePartial(`{"a": }`).Error() == nil {
+		t.Error("expected error for invalid input")
+	}
+}
+
+func TestParsePartialEmpty(t *testing.T) {
+	for _, in := range []string{"", "  \n", "-"} {
+		v := ParsePartial(in)
+		if err := v.Error(); err != nil || v.Kind() != Null || !v.Incomplete() {
+			t.Errorf("%q: expected an incomplete null, got %v, %v", in, v.Kind(), err)
+		}
+	}
+}
+
+func TestParsePartialPositions(t *testing.T) {
+	// The completed text is ["ab", true], longer than the input.
+	v := ParsePartial(`["ab", tr`)
+	pos, err := v.Index(1).Position()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Offset != 7 || pos.End != 11 || pos.Line != 1 || pos.Column != 8 {
+		t.Errorf("unexpected position %+v", pos)
+	}
+	if pos, _ := v.Position(); pos.End != 12 {
+		t.Errorf("expected the root to end at 12, got %d", pos.End)
+	}
+}
@@ -0,0 +1,71 @@
+package jchain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// source maps byte offsets of an input to lines and columns.
+type source struct {
+	file  string
+	size  int
+	lines []int // offset at which each line starts
+}
+
+func newSource(file string, input string) *source {
+	s := &source{file: file, size: len(input), lines: []int{0}}
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+	return s
+}
+
+// lineCol returns the 1-based line and byte column of pos.
+func (s *source) lineCol(pos int) (int, int) {
+	if pos > s.size {
+		pos = s.size
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	line := sort.Search(len(s.lines), func(i int) bool { return s.lines[i] > pos })
+	return line, pos - s.lines[line-1] + 1
+}
+
+// Position locates a value in the parsed input. Offset and End delimit the
+// value's bytes; Line and Column are 1-based and refer to Offset.
+type Position struct {
+	File   string
+	Offset int
+	End    int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Position reports where the value was found. It is only available for
+// values reached from a document parsed with ParseOptions.Positions.
+func (v *Value) Position() (Position, error) {
+	if v.err != nil {
+		return Position{}, v.err
+	}
+	if v.span == nil || v.src == nil {
+		return Position{}, fmt.Errorf("no position")
+	}
+	line, col := v.src.lineCol(v.span.start)
+	return Position{
+		File:   v.src.file,
+		Offset: v.span.start,
+		End:    v.span.end,
+		Line:   line,
+		Column: col,
+	}, nil
+}
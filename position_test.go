@@ -0,0 +1,50 @@
+package jchain
+
+import (
+	"testing"
+)
+
+func TestPosition(t *testing.T) {
+	json := "{\n  \"server\": {\n    \"port\": 0,\n    \"hosts\": [\"a\", \"b\"]\n  }\n}"
+	v := ParseWithOptions(json, ParseOptions{Positions: true, File: "config.json"})
+	if err := v.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := v.Get("server").Get("port").Position()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.String() != "config.json:3:13" || json[pos.Offset:pos.End] != "0" {
+		t.Errorf("unexpected position %s (%d-%d)", pos, pos.Offset, pos.End)
+	}
+
+	pos, err = v.Get("server").Get("hosts").Slice(1, 2).Index(0).Position()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.String() != "config.json:4:20" || json[pos.Offset:pos.End] != `"b"` {
+		t.Errorf("unexpected position %s (%d-%d)", pos, pos.Offset, pos.End)
+	}
+
+	pos, _ = v.Position()
+	if pos.Line != 1 || pos.Column != 1 || pos.End != len(json) {
+		t.Errorf("unexpected root position %+v", pos)
+	}
+
+	if _, err := Parse(json).Get("server").Position(); err == nil {
+		t.Error("expected error without recorded positions")
+	}
+}
+
+func TestLineCol(t *testing.T) {
+	src := newSource("", "ab\ncd\n\ne")
+	tests := []struct{ pos, line, col int }{
+		{0, 1, 1}, {2, 1, 3}, {3, 2, 1}, {6, 3, 1}, {7, 4, 1}, {8, 4, 2}, {100, 4, 2},
+	}
+	for _, tt := range tests {
+		if line, col := src.lineCol(tt.pos); line != tt.line || col != tt.col {
+			t.Errorf("lineCol(%d) = %d:%d, want %d:%d", tt.pos, line, col, tt.line, tt.col)
+		}
+	}
+}
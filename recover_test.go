@@ -0,0 +1,138 @@
+package jchain
+
+import (
+	"testing"
+)
+
+func TestParseRecover(t *testing.T) {
+	json := `{
+	"name": "svc",
+	"port": -x,
+	"hosts": ["a", tru, "c"],
+	"tags": ["x" "y"],
+	"limits": {"cpu": 1, "mem" 2, "disk": 3},
+	"ok": true
+}`
+	v, errs := ParseRecover(json, ParseOptions{})
+	if len(errs) != 4 {
+		for _, err := range errs {
+			t.Log(err)
+		}
+		t.Fatalf("expected 4 errors, got %d", len(errs))
+	}
+	wantLines := []int{3, 4, 5, 6}
+	for i, err := range errs {
+		if err.Line != wantLines[i] {
+			t.Errorf("error %d: expected line %d, got %d: %v", i, wantLines[i], err.Line, err)
+		}
+	}
+
+	if name, _ := v.Get("name").String(); name != "svc" {
+		t.Errorf("expected name svc, got %q", name)
+	}
+	if err := v.Get("port").Error(); err != errs[0] {
+		t.Errorf("expected placeholder for port to carry its error, got %v", err)
+	}
+	if h, _ := v.Get("hosts").Index(2).String(); h != "c" {
+		t.Errorf("expected c after the broken element, got %q", h)
+	}
+	if v.Get("hosts").Index(1).Error() == nil {
+		t.Error("expected placeholder for broken element")
+	}
+	if tags, _ := v.Get("tags").Array(); len(tags) != 2 {
+		t.Errorf("expected missing comma to be bridged, got %v", tags)
+	}
+	if mem, _ := v.Get("limits").Get("mem").Int(); mem != 2 {
+		t.Errorf("expected missing colon to be bridged, got %d", mem)
+	}
+	if disk, _ := v.Get("limits").Get("disk").Int(); disk != 3 {
+		t.Errorf("expected disk 3, got %d", disk)
+	}
+	if ok, _ := v.Get("ok").Bool(); !ok {
+		t.Error("expected members after errors to be parsed")
+	}
+}
+
+func TestParseRecoverUnclosed(t *testing.T) {
+	v, errs := ParseRecover(`{"a": [1, 2, {"b": "c"`, ParseOptions{})
+	if len(errs) != 1 || errs[0].Opened == nil {
+		t.Fatalf("expected a single unclosed error, got %v", errs)
+	}
+	if b, _ := v.Get("a").Index(2).Get("b").String(); b != "c" {
+		t.Errorf("expected partial tree, got %v", v.data)
+	}
+
+	v, errs = ParseRecover(`[1, 2]`, ParseOptions{})
+	if len(errs) != 0 || v.Error() != nil {
+		t.Errorf("expected no errors for valid input, got %v", errs)
+	}
+
+	v, errs = ParseRecover(`{"a": [1, 2, ], "b": {"c": 1, }}`, ParseOptions{Positions: true})
+	if len(errs) != 2 || errs[0].Column != 14 {
+		t.Errorf("expected both trailing commas to be reported, got %v", errs)
+	}
+	if !v.Equal(Parse(`{"a": [1, 2], "b": {"c": 1}}`)) || v.Get("a").Index(2).Error() == nil {
+		t.Errorf("expected no placeholder after a trailing comma, got %v", v.data)
+	}
+
+	v, errs = ParseRecover(`@`, ParseOptions{})
+	if len(errs) != 1 || v.Error() != errs[0] {
+		t.Errorf("expected the root error, got %v and %v", v.Error(), errs)
+	}
+}
+
+func TestParseRecoverPositions(t *testing.T) {
+	v, errs := ParseRecover(`[1, @, {"a": 3}]`, ParseOptions{Positions: true})
+	if len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", errs)
+	}
+	pos, err := v.Index(2).Get("a").Position()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Column != 14 {
+		t.Errorf("expected column 14, got %d", pos.Column)
+	}
+}
+
+func TestParseRecoverPlaceholders(t *testing.T) {
+	v, errs := ParseRecover(`{"a": [1, @, 3], "b": @}`, ParseOptions{})
+	if len(errs) != 2 {
+		t.Fatalf("expected two errors, got %v", errs)
+	}
+
+	// Data leaving the tree holds null where the placeholders are.
+	arr, err := v.Get("a").Array()
+	if err != nil || len(arr) != 3 || arr[1] != nil {
+		t.Errorf("expected [1, nil, 3], got %#v, %v", arr, err)
+	}
+	if obj, _ := v.Map(); obj["b"] != nil {
+		t.Errorf("expected nil for b, got %#v", obj["b"])
+	}
+	if data, _ := v.Any(); !Parse(`{"a": [1, null, 3], "b": null}`).Equal(From(data)) {
+		t.Errorf("unexpected data %#v", data)
+	}
+	if !v.Equal(Parse(`{"a": [1, null, 3], "b": null}`)) {
+		t.Error("expected placeholders to compare as null")
+	}
+	if out, err := v.MarshalJSON(); err != nil || string(out) != `{"a":[1,null,3],"b":null}` {
+		t.Errorf("unexpected encoding %s, %v", out, err)
+	}
+	if out, err := v.Canonical(); err != nil || string(out) != `{"a":[1,null,3],"b":null}` {
+		t.Errorf("unexpected canonical encoding %s, %v", out, err)
+	}
+
+	// Get, Index and Slice still reach the errors.
+	if err := v.Get("a").Index(1).Error(); err != errs[0] {
+		t.Errorf("expected the placeholder error, got %v", err)
+	}
+	if err := v.Get("a").Slice(1, 3).Index(0).Error(); err != errs[0] {
+		t.Errorf("expected the placeholder error through Slice, got %v", err)
+	}
+	if n, _ := v.Get("a").Slice(1, 3).Index(1).Int(); n != 3 {
+		t.Errorf("expected 3, got %d", n)
+	}
+	if err := v.Get("b").Error(); err != errs[1] {
+		t.Errorf("expected the placeholder error, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package jchain
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The scanners in this file hold the lexical rules for numbers, escape
+// sequences and literals. The parser and the Tokenizer feed them one byte at
+// a time, so that both accept the same input and report the same errors.
+
+// lexError is an error found by a scanner. It belongs at the byte just fed,
+// or at the start of the escape sequence when atStart is set.
+type lexError struct {
+	msg     string
+	atStart bool
+}
+
+const (
+	numStart = iota
+	numMinus
+	numZero
+	numInt
+	numDot
+	numFrac
+	numExp
+	numExpSign
+	numExpDigits
+)
+
+// numberScanner checks the JSON number grammar.
+type numberScanner struct {
+	state   int
+	isFloat bool
+}
+
+// step reports whether c continues the number.
+func (s *numberScanner) step(c byte) bool {
+	digit := '0' <= c && c <= '9'
+	switch s.state {
+	case numStart, numMinus:
+		switch {
+		case c == '-' && s.state == numStart:
+			s.state = numMinus
+		case c == '0':
+			s.state = numZero
+		case digit:
+			s.state = numInt
+		default:
+			return false
+		}
+	case numInt, numZero:
+		switch {
+		case digit && s.state == numInt:
+		case c == '.':
+			s.state, s.isFloat = numDot, true
+		case c == 'e' || c == 'E':
+			s.state, s.isFloat = numExp, true
+		default:
+			return false
+		}
+	case numDot:
+		if !digit {
+			return false
+		}
+		s.state = numFrac
+	case numFrac:
+		switch {
+		case digit:
+		case c == 'e' || c == 'E':
+			s.state = numExp
+		default:
+			return false
+		}
+	case numExp:
+		switch {
+		case c == '+' || c == '-':
+			s.state = numExpSign
+		case digit:
+			s.state = numExpDigits
+		default:
+			return false
+		}
+	case numExpSign, numExpDigits:
+		if !digit {
+			return false
+		}
+		s.state = numExpDigits
+	}
+	return true
+}
+
+// complete reports whether the bytes so far form a number.
+func (s *numberScanner) complete() bool {
+	switch s.state {
+	case numZero, numInt, numFrac, numExpDigits:
+		return true
+	}
+	return false
+}
+
+// validNumber checks raw against the JSON number grammar.
+func validNumber(raw []byte) bool {
+	var s numberScanner
+	for _, c := range raw {
+		if !s.step(c) {
+			return false
+		}
+	}
+	return s.complete()
+}
+
+const (
+	escStart = iota
+	escHex
+	escBackslash
+	escU
+	escLowHex
+)
+
+// escapeScanner decodes an escape sequence from the bytes after its
+// backslash. The \u escape of a high surrogate takes in the \u escape of the
+// low surrogate after it.
+type escapeScanner struct {
+	state int
+	n     int
+	hi    rune
+	// r is the decoded rune once step reports that the sequence is done.
+	r rune
+}
+
+// step consumes c and reports whether the sequence is complete.
+func (s *escapeScanner) step(c byte) (bool, *lexError) {
+	switch s.state {
+	case escStart:
+		switch c {
+		case '"', '\\', '/':
+			s.r = rune(c)
+		case 'b':
+			s.r = '\b'
+		case 'f':
+			s.r = '\f'
+		case 'n':
+			s.r = '\n'
+		case 'r':
+			s.r = '\r'
+		case 't':
+			s.r = '\t'
+		case 'u':
+			s.state = escHex
+			return false, nil
+		default:
+			return false, &lexError{msg: "invalid escape sequence", atStart: true}
+		}
+		return true, nil
+	case escHex, escLowHex:
+		d, ok := hexDigit(c)
+		if !ok {
+			return false, &lexError{msg: "invalid unicode escape, expected 4 hex digits"}
+		}
+		s.r = s.r<<4 | d
+		if s.n++; s.n < 4 {
+			return false, nil
+		}
+		if s.state == escLowHex {
+			if s.r < 0xDC00 || s.r > 0xDFFF {
+				return false, &lexError{msg: "expected low surrogate after high surrogate", atStart: true}
+			}
+			s.r = utf16.DecodeRune(s.hi, s.r)
+			return true, nil
+		}
+		switch {
+		case 0xD800 <= s.r && s.r <= 0xDBFF:
+			s.state, s.hi, s.r, s.n = escBackslash, s.r, 0, 0
+			return false, nil
+		case 0xDC00 <= s.r && s.r <= 0xDFFF:
+			return false, &lexError{msg: "unexpected low surrogate", atStart: true}
+		}
+		return true, nil
+	case escBackslash, escU:
+		if s.state == escBackslash && c == '\\' {
+			s.state = escU
+			return false, nil
+		}
+		if s.state == escU && c == 'u' {
+			s.state = escLowHex
+			return false, nil
+		}
+		return false, &lexError{msg: "expected low surrogate after high surrogate"}
+	}
+	return false, nil
+}
+
+// eofMessage describes the input ending inside the sequence. It is empty
+// when nothing followed the backslash, which callers report as an
+// unterminated string.
+func (s *escapeScanner) eofMessage() string {
+	switch s.state {
+	case escHex, escLowHex:
+		return "invalid unicode escape, expected 4 hex digits"
+	case escBackslash, escU:
+		return "expected low surrogate after high surrogate"
+	}
+	return ""
+}
+
+func hexDigit(c byte) (rune, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return rune(c - '0'), true
+	case 'a' <= c && c <= 'f':
+		return rune(c - 'a' + 10), true
+	case 'A' <= c && c <= 'F':
+		return rune(c - 'A' + 10), true
+	}
+	return 0, false
+}
+
+// stringRuneError checks a rune of a string's contents, as returned by
+// utf8.DecodeRune, and returns a message when it is not allowed.
+func stringRuneError(r rune, size int) string {
+	switch {
+	case r == utf8.RuneError && size == 1:
+		return "invalid UTF-8 in string"
+	case r < 0x20:
+		return "invalid control character in string"
+	}
+	return ""
+}
+
+// literalText returns the literal starting with c, or "" if there is none.
+func literalText(c byte) string {
+	switch c {
+	case 't':
+		return "true"
+	case 'f':
+		return "false"
+	case 'n':
+		return "null"
+	}
+	return ""
+}
@@ -0,0 +1,293 @@
+package jchain
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+	// A string field becomes an enum when it has at most maxEnumValues
+	// distinct values and each value was seen enumRepeat times on average.
+	maxEnumValues = 10
+	enumRepeat    = 2
+)
+
+// shape accumulates everything observed at one path across all samples.
+type shape struct {
+	seen    int
+	kinds   map[Kind]int
+	objects int
+	props   map[string]*shape
+	items   *shape
+	// unsigned records an integer above math.MaxInt64, negative one below
+	// zero and big one outside both 64-bit ranges, which together decide
+	// the Go type of the integers.
+	unsigned bool
+	negative bool
+	big      bool
+	strs     map[string]int
+	nstr     int
+	formats  map[string]int
+}
+
+func newShape() *shape {
+	return &shape{
+		kinds:   make(map[Kind]int),
+		props:   make(map[string]*shape),
+		strs:    make(map[string]int),
+		formats: make(map[string]int),
+	}
+}
+
+func (s *shape) observe(val any) {
+	s.seen++
+	s.kinds[getKind(val)]++
+
+	switch val := val.(type) {
+	case map[string]any:
+		s.objects++
+		for key, elem := range val {
+			child, ok := s.props[key]
+			if !ok {
+				child = newShape()
+				s.props[key] = child
+			}
+			child.observe(elem)
+		}
+	case []any:
+		if s.items == nil {
+			s.items = newShape()
+		}
+		for _, elem := range val {
+			s.items.observe(elem)
+		}
+	case string:
+		s.nstr++
+		if s.strs != nil {
+			s.strs[val]++
+			if len(s.strs) > maxEnumValues {
+				s.strs = nil
+			}
+		}
+		s.formats[detectFormat(val)]++
+	case uint64:
+		s.unsigned = true
+	case *big.Int:
+		s.big = true
+	case int64:
+		if val < 0 {
+			s.negative = true
+		}
+	}
+}
+
+func (s *shape) has(k Kind) bool {
+	return s.kinds[k] > 0
+}
+
+func (s *shape) optional(key string) bool {
+	child, ok := s.props[key]
+	return !ok || child.seen < s.objects
+}
+
+func (s *shape) keys() []string {
+	keys := make([]string, 0, len(s.props))
+	for key := range s.props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *shape) format() string {
+	if s.nstr == 0 {
+		return ""
+	}
+	for f, n := range s.formats {
+		if f != "" && n == s.nstr {
+			return f
+		}
+	}
+	return ""
+}
+
+func (s *shape) enum() []string {
+	if s.strs == nil || s.nstr < enumRepeat*len(s.strs) {
+		return nil
+	}
+	vals := make([]string, 0, len(s.strs))
+	for val := range s.strs {
+		vals = append(vals, val)
+	}
+	sort.Strings(vals)
+	return vals
+}
+
+func (s *shape) schema() map[string]any {
+	out := make(map[string]any)
+
+	var types []any
+	for _, k := range []Kind{Object, Array, String, Int, Float, Bool, Null} {
+		if !s.has(k) || (k == Int && s.has(Float)) {
+			continue
+		}
+		types = append(types, schemaType(k))
+	}
+	if len(types) == 1 {
+		out["type"] = types[0]
+	} else if len(types) > 1 {
+		out["type"] = types
+	}
+
+	if s.objects > 0 {
+		props := make(map[string]any, len(s.props))
+		var required []any
+		for _, key := range s.keys() {
+			props[key] = s.props[key].schema()
+			if !s.optional(key) {
+				required = append(required, key)
+			}
+		}
+		out["properties"] = props
+		if len(required) > 0 {
+			out["required"] = required
+		}
+	}
+
+	if s.items != nil {
+		out["items"] = s.items.schema()
+	}
+
+	if f := s.format(); f != "" {
+		out["format"] = f
+	} else if vals := s.enum(); vals != nil && s.kinds[String]+s.kinds[Null] == s.seen {
+		enum := make([]any, 0, len(vals)+1)
+		for _, val := range vals {
+			enum = append(enum, val)
+		}
+		if s.has(Null) {
+			enum = append(enum, nil)
+		}
+		out["enum"] = enum
+	}
+
+	return out
+}
+
+func schemaType(k Kind) string {
+	switch k {
+	case Object:
+		return "object"
+	case Array:
+		return "array"
+	case String:
+		return "string"
+	case Int:
+		return "integer"
+	case Float:
+		return "number"
+	case Bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+func detectFormat(s string) string {
+	switch {
+	case isUUID(s):
+		return "uuid"
+	case isDateTime(s):
+		return "date-time"
+	case isDate(s):
+		return "date"
+	case isEmail(s):
+		return "email"
+	case isURI(s):
+		return "uri"
+	}
+	return ""
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isHex(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func isDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+func isEmail(s string) bool {
+	at := strings.IndexByte(s, '@')
+	if at <= 0 || at != strings.LastIndexByte(s, '@') || strings.ContainsAny(s, " \t\r\n") {
+		return false
+	}
+	domain := s[at+1:]
+	dot := strings.IndexByte(domain, '.')
+	return dot > 0 && dot < len(domain)-1
+}
+
+func isURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func inferShape(samples []*Value) (*shape, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no samples")
+	}
+	s := newShape()
+	for _, sample := range samples {
+		if sample.err != nil {
+			return nil, sample.err
+		}
+		s.observe(sample.data)
+	}
+	return s, nil
+}
+
+// InferSchema derives a JSON Schema (draft 2020-12) describing every sample.
+// Types seen at the same path are merged, object keys missing from some
+// samples are left out of "required", low-cardinality strings become enums
+// and common string formats such as date-time and uuid are detected.
+func InferSchema(samples ...*Value) *Value {
+	s, err := inferShape(samples)
+	if err != nil {
+		return &Value{err: err}
+	}
+	schema := s.schema()
+	schema["$schema"] = schemaDialect
+	return &Value{kind: Object, data: schema}
+}
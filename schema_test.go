@@ -0,0 +1,70 @@
+package jchain
+
+import (
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	samples := []*Value{
+		Parse(`{"id": "0b7c3a5e-8f9d-4c1a-9e2b-3d4f5a6b7c8d", "status": "active", "count": 1, "created": "2024-01-02T15:04:05Z", "tags": ["a"]}`),
+		Parse(`{"id": "1c8d4b6f-9a0e-4d2b-8f3c-4e5a6b7c8d9e", "status": "active", "count": 2.5, "created": "2024-02-03T10:00:00+01:00", "tags": [], "note": null}`),
+		Parse(`{"id": "2d9e5c7a-0b1f-4e3c-9a4d-5f6b7c8d9eaf", "status": "inactive", "count": 3, "created": "2024-03-04T00:00:00Z", "tags": ["b", "c"], "note": "x"}`),
+		Parse(`{"id": "3eaf6d8b-1c2a-4f4d-8b5e-6a7c8d9eafb0", "status": "active", "count": 4, "created": "2024-04-05T00:00:00Z", "tags": ["d"]}`),
+	}
+	schema := InferSchema(samples...)
+	if err := schema.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	props := schema.Get("properties")
+	if f, _ := props.Get("id").Get("format").String(); f != "uuid" {
+		t.Errorf("expected uuid format for id, got %q", f)
+	}
+	if f, _ := props.Get("created").Get("format").String(); f != "date-time" {
+		t.Errorf("expected date-time format for created, got %q", f)
+	}
+	if typ, _ := props.Get("count").Get("type").String(); typ != "number" {
+		t.Errorf("expected number type for count, got %q", typ)
+	}
+	if typ, _ := props.Get("tags").Get("items").Get("type").String(); typ != "string" {
+		t.Errorf("expected string items for tags, got %q", typ)
+	}
+
+	enum, err := props.Get("status").Get("enum").Array()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enum) != 2 || enum[0] != "active" || enum[1] != "inactive" {
+		t.Errorf("unexpected enum for status: %v", enum)
+	}
+
+	noteType, err := props.Get("note").Get("type").Array()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(noteType) != 2 || noteType[0] != "string" || noteType[1] != "null" {
+		t.Errorf("unexpected type for note: %v", noteType)
+	}
+
+	required, err := schema.Get("required").Array()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range required {
+		if key == "note" {
+			t.Error("note should be optional")
+		}
+	}
+	if len(required) != 5 {
+		t.Errorf("expected 5 required keys, got %v", required)
+	}
+}
+
+func TestInferSchemaErrors(t *testing.T) {
+	if InferSchema().Error() == nil {
+		t.Error("expected error without samples")
+	}
+	if InferSchema(Parse(`{`)).Error() == nil {
+		t.Error("expected parse error to propagate")
+	}
+}
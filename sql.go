@@ -0,0 +1,84 @@
+package jchain
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// SQLValue holds a JSON column value, such as a Postgres jsonb or a SQLite
+// text column. It implements sql.Scanner and driver.Valuer:
+//
+//	var doc jchain.SQLValue
+//	err := db.QueryRow(`SELECT data FROM events WHERE id = $1`, id).Scan(&doc)
+//	name, err := doc.Get("user").Get("name").String()
+//
+// SQL NULL scans to a nil JSON and a nil JSON is stored as SQL NULL, while
+// a JSON null is the text null, so the two stay apart as they do in
+// the database.
+type SQLValue struct {
+	JSON *Value
+	// Options configures how scanned text is parsed. When nil, Scan uses the
+	// limits of Parse.
+	Options *ParseOptions
+}
+
+// Scan implements sql.Scanner for []byte and string sources.
+func (s *SQLValue) Scan(src any) error {
+	var text string
+	switch val := src.(type) {
+	case nil:
+		s.JSON = nil
+		return nil
+	case []byte:
+		text = string(val)
+	case string:
+		text = val
+	default:
+		return fmt.Errorf("cannot scan %T into SQLValue", src)
+	}
+
+	var v *Value
+	if s.Options != nil {
+		v = ParseWithOptions(text, *s.Options)
+	} else {
+		v = Parse(text)
+	}
+	if v.err != nil {
+		return v.err
+	}
+	s.JSON = v
+	return nil
+}
+
+// Value implements driver.Valuer, encoding the JSON as compact text.
+func (s SQLValue) Value() (driver.Value, error) {
+	if s.JSON == nil {
+		return nil, nil
+	}
+	if s.JSON.err != nil {
+		return nil, s.JSON.err
+	}
+	text, err := appendJSON(nil, s.JSON.data)
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// Get starts a chain on the scanned value. After scanning SQL NULL the
+// chain reports that there is no value.
+func (s SQLValue) Get(key string) *Value {
+	return s.value().Get(key)
+}
+
+// Index starts a chain on the scanned value.
+func (s SQLValue) Index(i int) *Value {
+	return s.value().Index(i)
+}
+
+func (s SQLValue) value() *Value {
+	if s.JSON == nil {
+		return &Value{err: fmt.Errorf("no value scanned")}
+	}
+	return s.JSON
+}
@@ -0,0 +1,164 @@
+package jchain
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// fakeDriver stores a single column of rows. "insert" appends its argument
+// and "select" returns every row, with []byte for stored strings the way
+// most drivers return text columns.
+type fakeDriver struct {
+	rows []driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.query != "insert" {
+		return nil, fmt.Errorf("unknown query %q", s.query)
+	}
+	s.d.rows = append(s.d.rows, args[0])
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.query != "select" {
+		return nil, fmt.Errorf("unknown query %q", s.query)
+	}
+	return &fakeRows{rows: s.d.rows}, nil
+}
+
+type fakeRows struct {
+	rows []driver.Value
+	i    int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"data"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	dest[0] = r.rows[r.i]
+	if s, ok := dest[0].(string); ok {
+		dest[0] = []byte(s)
+	}
+	r.i++
+	return nil
+}
+
+// fake is registered once, since database/sql panics when a driver name is
+// registered twice, as happens when tests run with -count.
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register("jchain-fake", fake)
+}
+
+func TestSQLValue(t *testing.T) {
+	fake.rows = nil
+	db, err := sql.Open("jchain-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	inserts := []SQLValue{
+		{JSON: Parse(`{"user": {"name": "Alice", "tags": ["a", "b"]}}`)},
+		{JSON: Parse(`null`)},
+		{},
+		{JSON: Parse(`[null]`)},
+	}
+	for _, v := range inserts {
+		if _, err := db.Exec("insert", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fake.rows[0] != `{"user":{"name":"Alice","tags":["a","b"]}}` || fake.rows[1] != "null" || fake.rows[2] != nil {
+		t.Errorf("unexpected stored rows %q", fake.rows)
+	}
+	if _, err := db.Exec("insert", SQLValue{JSON: Parse(`{`)}); err == nil {
+		t.Error("expected error when storing an invalid value")
+	}
+
+	rows, err := db.Query("select")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []SQLValue
+	for rows.Next() {
+		var v SQLValue
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(got))
+	}
+	if name, err := got[0].Get("user").Get("name").String(); err != nil || name != "Alice" {
+		t.Errorf("got %q, %v", name, err)
+	}
+	if tag, err := got[0].Get("user").Get("tags").Index(1).String(); err != nil || tag != "b" {
+		t.Errorf("got %q, %v", tag, err)
+	}
+	if got[1].JSON == nil || got[1].JSON.Kind() != Null {
+		t.Errorf("expected the text null to scan as JSON null, got %v", got[1].JSON)
+	}
+	if got[2].JSON != nil || got[2].Get("a").Error() == nil {
+		t.Errorf("expected SQL NULL to scan as no value, got %v", got[2].JSON)
+	}
+	if !got[3].JSON.Equal(Parse(`[null]`)) {
+		t.Errorf("got %v", got[3].JSON)
+	}
+}
+
+func TestSQLValueScan(t *testing.T) {
+	var v SQLValue
+	if err := v.Scan(`[1, 2]`); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := v.Index(1).Int(); n != 2 {
+		t.Errorf("got %d", n)
+	}
+
+	if err := v.Scan(42); err == nil || err.Error() != "cannot scan int into SQLValue" {
+		t.Errorf("expected unsupported source error, got %v", err)
+	}
+
+	limited := SQLValue{Options: &ParseOptions{MaxDepth: 2}}
+	if err := limited.Scan([]byte(`[[[1]]]`)); err == nil {
+		t.Error("expected depth limit to be applied")
+	}
+	relaxed := SQLValue{Options: &ParseOptions{Relaxed: true}}
+	if err := relaxed.Scan(`{"a": 1, /* note */}`); err != nil {
+		t.Errorf("expected relaxed parsing, got %v", err)
+	}
+
+	var empty SQLValue
+	if err := empty.Get("a").Error(); err == nil || err.Error() != "no value scanned" {
+		t.Errorf("expected no value scanned, got %v", err)
+	}
+}
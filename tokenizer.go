@@ -0,0 +1,514 @@
+package jchain
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+type EventType int
+
+const (
+	EventBeginObject EventType = iota
+	EventKey
+	EventBeginArray
+	EventString
+	EventNumber
+	EventBool
+	EventNull
+	EventEnd
+)
+
+func (e EventType) String() string {
+	switch e {
+	case EventBeginObject:
+		return "BeginObject"
+	case EventKey:
+		return "Key"
+	case EventBeginArray:
+		return "BeginArray"
+	case EventString:
+		return "String"
+	case EventNumber:
+		return "Number"
+	case EventBool:
+		return "Bool"
+	case EventNull:
+		return "Null"
+	case EventEnd:
+		return "End"
+	default:
+		return "Invalid"
+	}
+}
+
+// Event is a single token. Value holds the decoded text of keys and strings
+// and the raw text of numbers; Bool holds the value of booleans. Offset is
+// the byte offset of the token in the input, and Depth the number of
+// containers enclosing it, so that an End has the Depth of its Begin.
+type Event struct {
+	Type   EventType
+	Value  string
+	Bool   bool
+	Offset int64
+	Depth  int
+}
+
+const (
+	tokValue = iota
+	tokFirstValue
+	tokFirstKey
+	tokKey
+	tokColon
+	tokComma
+	tokDone
+)
+
+// tokenizerMaxDepth limits nesting like the default of Parse.
+const tokenizerMaxDepth = 1000
+
+// Tokenizer reads JSON tokens one at a time without building a tree. Apart
+// from the current token it only keeps one byte per open container, and
+// nesting deeper than 1000 containers is a syntax error, as with Parse.
+type Tokenizer struct {
+	r    io.ByteScanner
+	off  int64
+	line int
+	col  int
+	// prevLine and prevCol are the position of the last byte read.
+	prevLine int
+	prevCol  int
+	// tokLine and tokCol are the position of the start of the last token.
+	tokLine int
+	tokCol  int
+	stack   []byte
+	state   int
+	buf     []byte
+	err     error
+	// begun is set when the last event opened an object or array.
+	begun bool
+	// discard makes strings and numbers read while skipping come back empty
+	// without being kept in buf.
+	discard bool
+}
+
+func NewTokenizer(r io.Reader) *Tokenizer {
+	if bs, ok := r.(io.ByteScanner); ok {
+		return &Tokenizer{r: bs, line: 1, col: 1}
+	}
+	return &Tokenizer{r: bufio.NewReader(r), line: 1, col: 1}
+}
+
+func NewStringTokenizer(s string) *Tokenizer {
+	return NewTokenizer(strings.NewReader(s))
+}
+
+func NewBytesTokenizer(b []byte) *Tokenizer {
+	return NewTokenizer(bytes.NewReader(b))
+}
+
+// Depth returns the number of containers currently open.
+func (t *Tokenizer) Depth() int {
+	return len(t.stack)
+}
+
+// Next returns the next event. After the top-level value has ended it
+// returns io.EOF; malformed input yields a *SyntaxError.
+func (t *Tokenizer) Next() (Event, error) {
+	if t.err != nil {
+		return Event{}, t.err
+	}
+	ev, err := t.next()
+	if err != nil {
+		t.err = err
+	}
+	t.begun = err == nil && (ev.Type == EventBeginObject || ev.Type == EventBeginArray)
+	return ev, err
+}
+
+// Skip consumes the rest of the object or array opened by the last event,
+// including its End, without allocating its keys or values. After any other
+// event it does nothing.
+func (t *Tokenizer) Skip() error {
+	if !t.begun {
+		return nil
+	}
+	target := len(t.stack) - 1
+	t.discard = true
+	defer func() { t.discard = false }()
+	for len(t.stack) > target {
+		if _, err := t.Next(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	t.begun = false
+	return nil
+}
+
+func (t *Tokenizer) next() (Event, error) {
+	for {
+		c, err := t.skipWhitespace()
+		if err == io.EOF {
+			if t.state == tokDone {
+				return Event{}, io.EOF
+			}
+			return Event{}, t.syntaxError(t.eofMessage(), -1)
+		}
+		if err != nil {
+			return Event{}, err
+		}
+		off := t.off - 1
+		t.tokLine, t.tokCol = t.prevLine, t.prevCol
+
+		switch t.state {
+		case tokDone:
+			return Event{}, t.syntaxError("unexpected data after top-level value", int(c))
+		case tokColon:
+			if c != ':' {
+				return Event{}, t.syntaxError("expected ':' after object key", int(c))
+			}
+			t.state = tokValue
+			continue
+		case tokComma:
+			open := t.stack[len(t.stack)-1]
+			switch {
+			case c == ',' && open == '{':
+				t.state = tokKey
+				continue
+			case c == ',':
+				t.state = tokValue
+				continue
+			case c == '}' && open == '{', c == ']' && open == '[':
+				return t.end(off), nil
+			case open == '{':
+				return Event{}, t.syntaxError("expected ',' or '}' after object member", int(c))
+			default:
+				return Event{}, t.syntaxError("expected ',' or ']' after array element", int(c))
+			}
+		case tokFirstKey, tokKey:
+			if c == '}' && t.state == tokFirstKey {
+				return t.end(off), nil
+			}
+			if c != '"' {
+				if t.state == tokFirstKey {
+					return Event{}, t.syntaxError("expected string key or '}'", int(c))
+				}
+				return Event{}, t.syntaxError("expected string key", int(c))
+			}
+			key, err := t.readString()
+			if err != nil {
+				return Event{}, err
+			}
+			t.state = tokColon
+			return Event{Type: EventKey, Value: key, Offset: off, Depth: len(t.stack)}, nil
+		case tokFirstValue:
+			if c == ']' {
+				return t.end(off), nil
+			}
+		}
+
+		return t.value(c, off)
+	}
+}
+
+func (t *Tokenizer) eofMessage() string {
+	switch t.state {
+	case tokColon:
+		return "expected ':' after object key"
+	case tokFirstKey:
+		return "expected string key or '}'"
+	case tokKey:
+		return "expected string key"
+	case tokComma:
+		if t.stack[len(t.stack)-1] == '{' {
+			return "expected ',' or '}' after object member"
+		}
+		return "expected ',' or ']' after array element"
+	default:
+		return "expected value"
+	}
+}
+
+func (t *Tokenizer) value(c byte, off int64) (Event, error) {
+	depth := len(t.stack)
+	if (c == '{' || c == '[') && depth >= tokenizerMaxDepth {
+		return Event{}, t.syntaxError("maximum depth exceeded", int(c))
+	}
+	switch {
+	case c == '{':
+		t.stack = append(t.stack, c)
+		t.state = tokFirstKey
+		return Event{Type: EventBeginObject, Offset: off, Depth: depth}, nil
+	case c == '[':
+		t.stack = append(t.stack, c)
+		t.state = tokFirstValue
+		return Event{Type: EventBeginArray, Offset: off, Depth: depth}, nil
+	case c == '"':
+		s, err := t.readString()
+		if err != nil {
+			return Event{}, err
+		}
+		t.afterValue()
+		return Event{Type: EventString, Value: s, Offset: off, Depth: depth}, nil
+	case c == '-' || ('0' <= c && c <= '9'):
+		raw, err := t.readNumber(c)
+		if err != nil {
+			return Event{}, err
+		}
+		t.afterValue()
+		return Event{Type: EventNumber, Value: raw, Offset: off, Depth: depth}, nil
+	case c == 't':
+		if err := t.readLiteral(literalText(c), off); err != nil {
+			return Event{}, err
+		}
+		t.afterValue()
+		return Event{Type: EventBool, Bool: true, Offset: off, Depth: depth}, nil
+	case c == 'f':
+		if err := t.readLiteral(literalText(c), off); err != nil {
+			return Event{}, err
+		}
+		t.afterValue()
+		return Event{Type: EventBool, Bool: false, Offset: off, Depth: depth}, nil
+	case c == 'n':
+		if err := t.readLiteral(literalText(c), off); err != nil {
+			return Event{}, err
+		}
+		t.afterValue()
+		return Event{Type: EventNull, Offset: off, Depth: depth}, nil
+	default:
+		return Event{}, t.syntaxError("expected value", int(c))
+	}
+}
+
+func (t *Tokenizer) end(off int64) Event {
+	t.stack = t.stack[:len(t.stack)-1]
+	t.afterValue()
+	return Event{Type: EventEnd, Offset: off, Depth: len(t.stack)}
+}
+
+func (t *Tokenizer) afterValue() {
+	if len(t.stack) == 0 {
+		t.state = tokDone
+	} else {
+		t.state = tokComma
+	}
+}
+
+func (t *Tokenizer) readByte() (byte, error) {
+	c, err := t.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	t.off++
+	t.prevLine, t.prevCol = t.line, t.col
+	if c == '\n' {
+		t.line++
+		t.col = 1
+	} else {
+		t.col++
+	}
+	return c, nil
+}
+
+func (t *Tokenizer) unreadByte() {
+	t.r.UnreadByte()
+	t.off--
+	t.line, t.col = t.prevLine, t.prevCol
+}
+
+func (t *Tokenizer) skipWhitespace() (byte, error) {
+	for {
+		c, err := t.readByte()
+		if err != nil {
+			return 0, err
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			return c, nil
+		}
+	}
+}
+
+const noFound = -2
+
+// syntaxError reports an error at found, the byte just read. At the end of
+// the input found is -1, and noFound reports at the current offset without
+// naming a byte.
+func (t *Tokenizer) syntaxError(msg string, found int) *SyntaxError {
+	e := &SyntaxError{Msg: msg, Offset: int(t.off), Line: t.line, Column: t.col}
+	switch found {
+	case -1:
+		e.Found = "end of input"
+		return e
+	case noFound:
+		return e
+	}
+	e.Offset--
+	e.Line, e.Column = t.prevLine, t.prevCol
+	switch {
+	case found >= utf8.RuneSelf:
+		e.Found = fmt.Sprintf("byte %#02x", found)
+	case found < 0x20:
+		e.Found = fmt.Sprintf("%q", rune(found))
+	default:
+		e.Found = "'" + string(rune(found)) + "'"
+	}
+	return e
+}
+
+// readLiteral reads the rest of lit. Like the parser it reports a mismatch at
+// the start of the literal.
+func (t *Tokenizer) readLiteral(lit string, off int64) error {
+	for i := 1; i < len(lit); i++ {
+		c, err := t.readByte()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == nil && c == lit[i] {
+			continue
+		}
+		found := lit[:i]
+		if err == nil && ('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			found += string(c)
+		}
+		return &SyntaxError{
+			Msg:    "invalid literal, expected '" + lit + "'",
+			Found:  "'" + found + "'",
+			Offset: int(off),
+			Line:   t.tokLine,
+			Column: t.tokCol,
+		}
+	}
+	return nil
+}
+
+func (t *Tokenizer) readNumber(first byte) (string, error) {
+	var num numberScanner
+	num.step(first)
+	t.buf = append(t.buf[:0], first)
+	for {
+		c, err := t.readByte()
+		if err == io.EOF {
+			if !num.complete() {
+				return "", t.syntaxError("invalid number, expected digit", -1)
+			}
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if !num.step(c) {
+			if !num.complete() {
+				return "", t.syntaxError("invalid number, expected digit", int(c))
+			}
+			t.unreadByte()
+			break
+		}
+		if !t.discard {
+			t.buf = append(t.buf, c)
+		}
+	}
+	if t.discard {
+		return "", nil
+	}
+	return string(t.buf), nil
+}
+
+func (t *Tokenizer) readString() (string, error) {
+	t.buf = t.buf[:0]
+	for {
+		c, err := t.readByte()
+		if err == io.EOF {
+			return "", t.syntaxError("unterminated string", -1)
+		}
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case c == '"':
+			if t.discard {
+				return "", nil
+			}
+			return string(t.buf), nil
+		case c == '\\':
+			if err := t.readEscape(); err != nil {
+				return "", err
+			}
+		case c < utf8.RuneSelf:
+			if msg := stringRuneError(rune(c), 1); msg != "" {
+				return "", t.syntaxError(msg, int(c))
+			}
+			if !t.discard {
+				t.buf = append(t.buf, c)
+			}
+		default:
+			if err := t.readRune(c); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// readRune reads the rest of the UTF-8 sequence starting with first.
+func (t *Tokenizer) readRune(first byte) error {
+	off, line, col := int(t.off)-1, t.prevLine, t.prevCol
+	start := len(t.buf)
+	t.buf = append(t.buf, first)
+	for !utf8.FullRune(t.buf[start:]) {
+		c, err := t.readByte()
+		if err == io.EOF {
+			return t.syntaxError("unterminated string", -1)
+		}
+		if err != nil {
+			return err
+		}
+		t.buf = append(t.buf, c)
+	}
+	if r, size := utf8.DecodeRune(t.buf[start:]); size < len(t.buf)-start || stringRuneError(r, size) != "" {
+		e := t.syntaxError("invalid UTF-8 in string", int(first))
+		e.Offset, e.Line, e.Column = off, line, col
+		return e
+	}
+	// The sequence only needed buf to be validated.
+	if t.discard {
+		t.buf = t.buf[:start]
+	}
+	return nil
+}
+
+func (t *Tokenizer) readEscape() error {
+	off, line, col := int(t.off)-1, t.prevLine, t.prevCol
+	var esc escapeScanner
+	for {
+		c, err := t.readByte()
+		if err == io.EOF {
+			msg := esc.eofMessage()
+			if msg == "" {
+				msg = "unterminated string"
+			}
+			return t.syntaxError(msg, -1)
+		}
+		if err != nil {
+			return err
+		}
+		done, lexErr := esc.step(c)
+		if lexErr != nil {
+			e := t.syntaxError(lexErr.msg, int(c))
+			if lexErr.atStart {
+				e.Offset, e.Line, e.Column, e.Found = off, line, col, `'\'`
+			}
+			return e
+		}
+		if done {
+			if !t.discard {
+				t.buf = utf8.AppendRune(t.buf, esc.r)
+			}
+			return nil
+		}
+	}
+}
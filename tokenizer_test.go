@@ -0,0 +1,145 @@
+package jchain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func collectEvents(tok *Tokenizer) ([]string, error) {
+	var out []string
+	for {
+		ev, err := tok.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		s := fmt.Sprintf("%s@%d/%d", ev.Type, ev.Offset, ev.Depth)
+		switch ev.Type {
+		case EventKey, EventString, EventNumber:
+			s += " " + ev.Value
+		case EventBool:
+			s += fmt.Sprint(" ", ev.Bool)
+		}
+		out = append(out, s)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	input := `{"a": [1, -2.5e3, "xé"], "b": {"c": true, "d": null}, "e": []}`
+	want := []string{
+		"BeginObject@0/0",
+		"Key@1/1 a",
+		"BeginArray@6/1",
+		"Number@7/2 1",
+		"Number@10/2 -2.5e3",
+		"String@18/2 xé",
+		"End@23/1",
+		"Key@26/1 b",
+		"BeginObject@31/1",
+		"Key@32/2 c",
+		"Bool@37/2 true",
+		"Key@43/2 d",
+		"Null@48/2",
+		"End@52/1",
+		"Key@55/1 e",
+		"BeginArray@60/1",
+		"End@61/1",
+		"End@62/0",
+	}
+
+	sources := map[string]*Tokenizer{
+		"string": NewStringTokenizer(input),
+		"bytes":  NewBytesTokenizer([]byte(input)),
+		"reader": NewTokenizer(iotest.OneByteReader(strings.NewReader(input))),
+	}
+	for name, tok := range sources {
+		got, err := collectEvents(tok)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%s: got\n%s\nwant\n%s", name, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestTokenizerScalar(t *testing.T) {
+	got, err := collectEvents(NewStringTokenizer("  42  "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "Number@2/0 42" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestTokenizerErrors(t *testing.T) {
+	tests := []struct {
+		json, msg string
+		line, col int
+	}{
+		{`[1, 2`, "expected ',' or ']' after array element", 1, 6},
+		{`{"a" 1}`, "expected ':' after object key", 1, 6},
+		{`{"a": 1,}`, "expected string key", 1, 9},
+		{"[1,\n 01]", "expected ',' or ']' after array element", 2, 3},
+		{"[1.]", "invalid number, expected digit", 1, 4},
+		{"[-", "invalid number, expected digit", 1, 3},
+		{`"\x"`, "invalid escape sequence", 1, 2},
+		{`"\u12g4"`, "invalid unicode escape, expected 4 hex digits", 1, 6},
+		{`"\udc00"`, "unexpected low surrogate", 1, 2},
+		{"\"\xe2\x82\"", "invalid UTF-8 in string", 1, 2},
+		{strings.Repeat("[", 1001), "maximum depth exceeded", 1, 1001},
+		{`[tru]`, "invalid literal, expected 'true'", 1, 2},
+		{"[\"a\nb\"]", "invalid control character in string", 1, 4},
+		{`"\ud800x"`, "expected low surrogate after high surrogate", 1, 8},
+		{`{} []`, "unexpected data after top-level value", 1, 4},
+		{``, "expected value", 1, 1},
+	}
+	for _, tt := range tests {
+		tok := NewStringTokenizer(tt.json)
+		_, err := collectEvents(tok)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: expected *SyntaxError, got %v", tt.json, err)
+			continue
+		}
+		if se.Msg != tt.msg || se.Line != tt.line || se.Column != tt.col {
+			t.Errorf("%q: got %q at %d:%d, want %q at %d:%d", tt.json, se.Msg, se.Line, se.Column, tt.msg, tt.line, tt.col)
+		}
+		if _, again := tok.Next(); again != err {
+			t.Errorf("%q: expected the error to repeat, got %v", tt.json, again)
+		}
+		// Parse shares the lexical rules and reports the same errors.
+		if pe, ok := Parse(tt.json).Error().(*SyntaxError); !ok || pe.Msg != se.Msg || pe.Line != se.Line || pe.Column != se.Column {
+			t.Errorf("%q: Parse reported %v", tt.json, Parse(tt.json).Error())
+		}
+	}
+}
+
+func TestTokenizerSkipBuffer(t *testing.T) {
+	big := strings.Repeat("é\\n", 1<<16)
+	tok := NewStringTokenizer(`{"skip": ["` + big + `", ` + strings.Repeat("1", 1<<16) + `], "keep": "x"}`)
+	for _, want := range []EventType{EventBeginObject, EventKey, EventBeginArray} {
+		if ev, err := tok.Next(); err != nil || ev.Type != want {
+			t.Fatalf("got %v, %v, want %v", ev.Type, err, want)
+		}
+	}
+	if err := tok.Skip(); err != nil {
+		t.Fatal(err)
+	}
+	if cap(tok.buf) > 64 {
+		t.Errorf("expected skipped values to stay out of the buffer, which grew to %d bytes", cap(tok.buf))
+	}
+	if ev, err := tok.Next(); err != nil || ev.Value != "keep" {
+		t.Errorf("got %v, %v", ev, err)
+	}
+	if ev, err := tok.Next(); err != nil || ev.Value != "x" {
+		t.Errorf("got %v, %v", ev, err)
+	}
+}
@@ -0,0 +1,770 @@
+// Package toml reads TOML 1.0 documents as jchain values, so that TOML
+// configuration can be traversed with the same API as JSON.
+//
+// Tables and inline tables become objects and arrays, including arrays of
+// tables, become arrays. Integers are int64 and floats float64. Infinity and
+// NaN have no JSON equivalent, so rather than failing the document they
+// become the strings "inf", "-inf" and "nan". Datetimes become strings that
+// Value.Time reads back: offset datetimes in RFC 3339, and local datetimes,
+// dates and times in the LocalDateTime, LocalDate and LocalTime layouts.
+//
+// Errors are *jchain.SyntaxError values with the line and column of the
+// problem.
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mntwlds/jchain"
+	"github.com/mntwlds/jchain/internal/core"
+)
+
+// Layouts of the strings local datetimes, dates and times decode to, for
+// use with Value.Time.
+const (
+	LocalDateTime = "2006-01-02T15:04:05.999999999"
+	LocalDate     = "2006-01-02"
+	LocalTime     = "15:04:05.999999999"
+)
+
+// maxDepth matches the limit of jchain.Parse.
+const maxDepth = 1000
+
+// Decode reads a TOML document.
+func Decode(input string) *jchain.Value {
+	root, err := parse(input)
+	if err != nil {
+		return core.Error(err).(*jchain.Value)
+	}
+	return jchain.From(root.data())
+}
+
+func parse(input string) (root *table, err error) {
+	p := &parser{src: input}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*jchain.SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			root, err = nil, e
+		}
+	}()
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		if r == utf8.RuneError && size == 1 {
+			p.fail(i, "invalid UTF-8")
+		}
+		i += size
+	}
+	return p.document(), nil
+}
+
+type tableKind int
+
+const (
+	// implicit tables were created as the parents of a header and may
+	// still be defined by one.
+	implicit tableKind = iota
+	// header tables were defined by a [table] header or are elements of an
+	// array of tables.
+	header
+	// dotted tables were created by dotted keys.
+	dotted
+	// inline tables cannot be extended.
+	inline
+)
+
+type table struct {
+	keys map[string]any
+	kind tableKind
+}
+
+// array is an array value, or an array of tables when tables is set.
+type array struct {
+	items  []any
+	tables bool
+}
+
+func newTable(kind tableKind) *table {
+	return &table{keys: map[string]any{}, kind: kind}
+}
+
+func (t *table) data() map[string]any {
+	m := make(map[string]any, len(t.keys))
+	for k, v := range t.keys {
+		m[k] = data(v)
+	}
+	return m
+}
+
+func data(v any) any {
+	switch v := v.(type) {
+	case *table:
+		return v.data()
+	case *array:
+		items := make([]any, len(v.items))
+		for i, item := range v.items {
+			items[i] = data(item)
+		}
+		return items
+	}
+	return v
+}
+
+type parser struct {
+	src   string
+	pos   int
+	depth int
+}
+
+func (p *parser) fail(pos int, format string, args ...any) {
+	panic(core.SyntaxError(p.src, pos, fmt.Sprintf(format, args...)))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek(n int) byte {
+	if p.pos+n < len(p.src) {
+		return p.src[p.pos+n]
+	}
+	return 0
+}
+
+func (p *parser) enter() {
+	p.depth++
+	if p.depth > maxDepth {
+		p.fail(p.pos, "maximum depth exceeded")
+	}
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) skipBlanks() {
+	for c := p.peek(0); c == ' ' || c == '\t'; c = p.peek(0) {
+		p.pos++
+	}
+}
+
+// newline consumes a line break, LF or CRLF, if one is at p.pos.
+func (p *parser) newline() bool {
+	switch {
+	case p.peek(0) == '\n':
+		p.pos++
+	case p.peek(0) == '\r' && p.peek(1) == '\n':
+		p.pos += 2
+	default:
+		return false
+	}
+	return true
+}
+
+func isControl(c byte) bool {
+	return c < 0x20 && c != '\t' || c == 0x7f
+}
+
+func (p *parser) comment() {
+	for !p.eof() && p.src[p.pos] != '\n' {
+		if p.src[p.pos] == '\r' && p.peek(1) == '\n' {
+			break
+		}
+		if isControl(p.src[p.pos]) {
+			p.fail(p.pos, "control characters are not allowed in comments")
+		}
+		p.pos++
+	}
+}
+
+// skipSpace skips blanks, line breaks and comments, as allowed inside
+// arrays.
+func (p *parser) skipSpace() {
+	for {
+		p.skipBlanks()
+		if p.peek(0) == '#' {
+			p.comment()
+		}
+		if !p.newline() {
+			return
+		}
+	}
+}
+
+// lineEnd checks that only a comment follows on the line and consumes the
+// line break.
+func (p *parser) lineEnd() {
+	p.skipBlanks()
+	if p.peek(0) == '#' {
+		p.comment()
+	}
+	if !p.eof() && !p.newline() {
+		p.fail(p.pos, "expected end of line")
+	}
+}
+
+func (p *parser) document() *table {
+	root := newTable(header)
+	current := root
+	for {
+		p.skipBlanks()
+		switch {
+		case p.eof():
+			return root
+		case p.newline():
+			continue
+		case p.peek(0) == '#':
+			p.comment()
+		case p.peek(0) == '[':
+			current = p.header(root)
+		default:
+			p.keyValue(current)
+		}
+		p.lineEnd()
+	}
+}
+
+func isBareKey(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// key reads a dotted key and the blanks after it.
+func (p *parser) key() []string {
+	var parts []string
+	for {
+		p.skipBlanks()
+		switch c := p.peek(0); {
+		case c == '"':
+			parts = append(parts, p.basicString(false))
+		case c == '\'':
+			parts = append(parts, p.literalString(false))
+		case isBareKey(c):
+			start := p.pos
+			for isBareKey(p.peek(0)) {
+				p.pos++
+			}
+			parts = append(parts, p.src[start:p.pos])
+		default:
+			p.fail(p.pos, "expected a key")
+		}
+		p.skipBlanks()
+		if p.peek(0) != '.' {
+			return parts
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) keyValue(t *table) {
+	start := p.pos
+	parts := p.key()
+	if p.peek(0) != '=' {
+		p.fail(p.pos, "expected '=' after key")
+	}
+	p.pos++
+	p.skipBlanks()
+	p.set(t, parts, p.value(), start)
+}
+
+// set stores val under a dotted key of t, creating the intermediate tables.
+func (p *parser) set(t *table, parts []string, val any, pos int) {
+	for i, k := range parts[:len(parts)-1] {
+		existing, ok := t.keys[k]
+		if !ok {
+			child := newTable(dotted)
+			t.keys[k] = child
+			t = child
+			continue
+		}
+		child, isTable := existing.(*table)
+		if !isTable {
+			p.fail(pos, "key %s is not a table", strings.Join(parts[:i+1], "."))
+		}
+		if child.kind != dotted {
+			p.fail(pos, "table %s already defined", strings.Join(parts[:i+1], "."))
+		}
+		t = child
+	}
+	last := parts[len(parts)-1]
+	if _, dup := t.keys[last]; dup {
+		p.fail(pos, "duplicate key %s", strings.Join(parts, "."))
+	}
+	t.keys[last] = val
+}
+
+// header reads a [table] or [[array]] header and returns the table that
+// following keys belong to.
+func (p *parser) header(root *table) *table {
+	start := p.pos
+	p.pos++
+	isArray := p.peek(0) == '['
+	if isArray {
+		p.pos++
+	}
+	parts := p.key()
+	if p.peek(0) != ']' || isArray && p.peek(1) != ']' {
+		p.fail(p.pos, "expected ']' after table name")
+	}
+	p.pos++
+	if isArray {
+		p.pos++
+	}
+
+	t := root
+	for i, k := range parts[:len(parts)-1] {
+		switch child := t.keys[k].(type) {
+		case nil:
+			next := newTable(implicit)
+			t.keys[k] = next
+			t = next
+		case *table:
+			if child.kind == inline {
+				p.fail(start, "cannot extend inline table %s", strings.Join(parts[:i+1], "."))
+			}
+			t = child
+		case *array:
+			if !child.tables {
+				p.fail(start, "cannot extend array %s", strings.Join(parts[:i+1], "."))
+			}
+			t = child.items[len(child.items)-1].(*table)
+		default:
+			p.fail(start, "key %s is not a table", strings.Join(parts[:i+1], "."))
+		}
+	}
+
+	name := strings.Join(parts, ".")
+	last := parts[len(parts)-1]
+	existing, exists := t.keys[last]
+	if isArray {
+		arr, ok := existing.(*array)
+		if !exists {
+			arr = &array{tables: true}
+			t.keys[last] = arr
+		} else if !ok || !arr.tables {
+			p.fail(start, "cannot append to %s, which is not an array of tables", name)
+		}
+		next := newTable(header)
+		arr.items = append(arr.items, next)
+		return next
+	}
+	if !exists {
+		next := newTable(header)
+		t.keys[last] = next
+		return next
+	}
+	if child, ok := existing.(*table); ok && child.kind == implicit {
+		child.kind = header
+		return child
+	}
+	p.fail(start, "table %s already defined", name)
+	return nil
+}
+
+func (p *parser) value() any {
+	switch c := p.peek(0); {
+	case c == '"':
+		return p.basicString(strings.HasPrefix(p.src[p.pos:], `"""`))
+	case c == '\'':
+		return p.literalString(strings.HasPrefix(p.src[p.pos:], "'''"))
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	}
+
+	start := p.pos
+	tok := p.token()
+	switch {
+	case tok == "":
+		p.fail(start, "expected a value")
+	case tok == "true":
+		return true
+	case tok == "false":
+		return false
+	case isDate(tok) && len(tok) == 10 && p.peek(0) == ' ' && isTimeStart(p.src[p.pos+1:]):
+		p.pos++
+		p.token()
+		tok = p.src[start:p.pos]
+		fallthrough
+	case isDate(tok) || isTimeStart(tok):
+		return p.datetime(tok, start)
+	}
+	return p.number(tok, start)
+}
+
+// token reads the characters a number, boolean or datetime can hold.
+func (p *parser) token() string {
+	start := p.pos
+	for c := p.peek(0); isBareKey(c) || c == '+' || c == '.' || c == ':'; c = p.peek(0) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isDate reports whether s starts with a full date, such as 1979-05-27.
+func isDate(s string) bool {
+	return len(s) >= 10 && isDigit(s[0]) && isDigit(s[3]) && s[4] == '-' && s[7] == '-'
+}
+
+// isTimeStart reports whether s starts with hours and a colon, such as 07:.
+func isTimeStart(s string) bool {
+	return len(s) >= 3 && isDigit(s[0]) && isDigit(s[1]) && s[2] == ':'
+}
+
+// datetime checks a datetime and normalizes it to the layout Value.Time
+// expects for its form.
+func (p *parser) datetime(tok string, pos int) string {
+	s := []byte(tok)
+	if len(s) > 10 && (s[10] == ' ' || s[10] == 't') {
+		s[10] = 'T'
+	}
+	if s[len(s)-1] == 'z' {
+		s[len(s)-1] = 'Z'
+	}
+	layout, out := LocalDateTime, LocalDateTime
+	switch {
+	case isTimeStart(tok):
+		layout, out = LocalTime, LocalTime
+	case len(s) == 10:
+		layout, out = LocalDate, LocalDate
+	case len(s) > 19 && strings.ContainsAny(string(s[19:]), "Z+-"):
+		layout, out = time.RFC3339Nano, time.RFC3339Nano
+	}
+	t, err := time.Parse(layout, string(s))
+	if err != nil {
+		p.fail(pos, "invalid date/time")
+	}
+	return t.Format(out)
+}
+
+// validUnderscores reports whether every underscore in s sits between two
+// digits.
+func validUnderscores(s string, digit func(byte) bool) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' && (i == 0 || i == len(s)-1 || !digit(s[i-1]) || !digit(s[i+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func (p *parser) number(tok string, pos int) any {
+	switch tok {
+	case "inf", "+inf":
+		return "inf"
+	case "-inf":
+		return "-inf"
+	case "nan", "+nan", "-nan":
+		return "nan"
+	}
+	if len(tok) > 2 && tok[0] == '0' && (tok[1] == 'x' || tok[1] == 'o' || tok[1] == 'b') {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[tok[1]]
+		digits := tok[2:]
+		if !validUnderscores(digits, isHexDigit) || strings.ContainsAny(digits, "+-") {
+			p.fail(pos, "invalid number")
+		}
+		return p.integer(strings.ReplaceAll(digits, "_", ""), base, pos)
+	}
+	if !validUnderscores(tok, isDigit) {
+		p.fail(pos, "invalid number")
+	}
+	clean := strings.ReplaceAll(tok, "_", "")
+	digits := strings.TrimLeft(clean, "+-")
+	if len(clean)-len(digits) > 1 {
+		p.fail(pos, "invalid number")
+	}
+	intPart := digits
+	if i := strings.IndexAny(digits, ".eE"); i >= 0 {
+		intPart = digits[:i]
+	}
+	if len(intPart) > 1 && intPart[0] == '0' {
+		p.fail(pos, "leading zeros are not allowed")
+	}
+	if intPart == digits {
+		return p.integer(clean, 10, pos)
+	}
+	if !isFloat(digits) {
+		p.fail(pos, "invalid number")
+	}
+	f, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		p.fail(pos, "%s cannot be represented in JSON", tok)
+	}
+	return f
+}
+
+func (p *parser) integer(s string, base int, pos int) int64 {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '+' || s[i] == '-' {
+			continue
+		}
+		if !isHexDigit(s[i]) {
+			p.fail(pos, "invalid number")
+		}
+	}
+	n, err := strconv.ParseInt(s, base, 64)
+	if err != nil {
+		if err.(*strconv.NumError).Err == strconv.ErrRange {
+			p.fail(pos, "out of range")
+		}
+		p.fail(pos, "invalid number")
+	}
+	return n
+}
+
+// isFloat matches an unsigned float without underscores: an integer part
+// followed by a fraction, an exponent or both.
+func isFloat(s string) bool {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	if i == 0 {
+		return false
+	}
+	exponent := false
+	if i < len(s) && s[i] == '.' {
+		i++
+		start := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		exponent = true
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		start := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	return i == len(s) && (exponent || strings.Contains(s, "."))
+}
+
+func (p *parser) array() *array {
+	open := p.pos
+	p.enter()
+	p.pos++
+	arr := &array{}
+	for {
+		p.skipSpace()
+		if p.eof() {
+			p.fail(open, "unterminated array")
+		}
+		if p.peek(0) == ']' {
+			p.pos++
+			break
+		}
+		arr.items = append(arr.items, p.value())
+		p.skipSpace()
+		if p.eof() {
+			p.fail(open, "unterminated array")
+		}
+		if p.peek(0) == ']' {
+			p.pos++
+			break
+		}
+		if p.peek(0) != ',' {
+			p.fail(p.pos, "expected ',' or ']'")
+		}
+		p.pos++
+	}
+	p.leave()
+	return arr
+}
+
+func (p *parser) inlineTable() *table {
+	p.enter()
+	p.pos++
+	t := newTable(dotted)
+	p.skipBlanks()
+	if p.peek(0) == '}' {
+		p.pos++
+	} else {
+		for {
+			start := p.pos
+			parts := p.key()
+			if p.peek(0) != '=' {
+				p.fail(p.pos, "expected '=' after key")
+			}
+			p.pos++
+			p.skipBlanks()
+			p.set(t, parts, p.value(), start)
+			p.skipBlanks()
+			if p.peek(0) == '}' {
+				p.pos++
+				break
+			}
+			if p.peek(0) != ',' {
+				p.fail(p.pos, "expected ',' or '}'")
+			}
+			p.pos++
+		}
+	}
+	p.leave()
+	freeze(t)
+	return t
+}
+
+// freeze marks an inline table, and the tables its dotted keys created, as
+// closed to extension.
+func freeze(t *table) {
+	t.kind = inline
+	for _, v := range t.keys {
+		if child, ok := v.(*table); ok && child.kind == dotted {
+			freeze(child)
+		}
+	}
+}
+
+// closing consumes the closing delimiter of a multi-line string, which may
+// be preceded by up to two quotes that belong to the content.
+func (p *parser) closing(sb *strings.Builder, q byte) {
+	n := 3
+	for n < 5 && p.peek(n) == q {
+		n++
+	}
+	for i := 3; i < n; i++ {
+		sb.WriteByte(q)
+	}
+	p.pos += n
+}
+
+func (p *parser) basicString(multi bool) string {
+	p.pos++
+	if multi {
+		p.pos += 2
+		p.newline()
+	}
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			p.fail(p.pos, "unterminated string")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '"' && !multi:
+			p.pos++
+			return sb.String()
+		case c == '"' && strings.HasPrefix(p.src[p.pos:], `"""`):
+			p.closing(&sb, '"')
+			return sb.String()
+		case c == '\\' && multi && p.lineEndingBackslash():
+		case c == '\\':
+			p.escape(&sb)
+		case multi && p.newline():
+			sb.WriteByte('\n')
+		case c == '\n' || c == '\r' && !multi:
+			p.fail(p.pos, "unterminated string")
+		case isControl(c):
+			p.fail(p.pos, "control characters must be escaped")
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// lineEndingBackslash skips a backslash at the end of a line in a multi-line
+// basic string, along with all whitespace and line breaks after it.
+func (p *parser) lineEndingBackslash() bool {
+	i := p.pos + 1
+	for i < len(p.src) && (p.src[i] == ' ' || p.src[i] == '\t') {
+		i++
+	}
+	if i < len(p.src) && p.src[i] == '\r' {
+		i++
+	}
+	if i >= len(p.src) || p.src[i] != '\n' {
+		return false
+	}
+	p.pos = i
+	for c := p.peek(0); c == ' ' || c == '\t' || c == '\n' || c == '\r'; c = p.peek(0) {
+		p.pos++
+	}
+	return true
+}
+
+var escapes = map[byte]byte{
+	'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', '"': '"', '\\': '\\',
+}
+
+func (p *parser) escape(sb *strings.Builder) {
+	start := p.pos
+	c := p.peek(1)
+	p.pos += 2
+	if e, ok := escapes[c]; ok {
+		sb.WriteByte(e)
+		return
+	}
+	n := 4
+	switch c {
+	case 'U':
+		n = 8
+	case 'u':
+	default:
+		p.fail(start, "invalid escape sequence")
+	}
+	if p.pos+n > len(p.src) {
+		p.fail(start, "invalid escape sequence")
+	}
+	v, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(v)) {
+		p.fail(start, "invalid escape sequence")
+	}
+	p.pos += n
+	sb.WriteRune(rune(v))
+}
+
+func (p *parser) literalString(multi bool) string {
+	p.pos++
+	if multi {
+		p.pos += 2
+		p.newline()
+	}
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			p.fail(p.pos, "unterminated string")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '\'' && !multi:
+			p.pos++
+			return sb.String()
+		case c == '\'' && strings.HasPrefix(p.src[p.pos:], "'''"):
+			p.closing(&sb, '\'')
+			return sb.String()
+		case multi && p.newline():
+			sb.WriteByte('\n')
+		case c == '\n' || c == '\r' && !multi:
+			p.fail(p.pos, "unterminated string")
+		case isControl(c):
+			p.fail(p.pos, "control characters are not allowed in literal strings")
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package toml
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mntwlds/jchain"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		toml, json string
+	}{
+		{"", `{}`},
+		{"# comment\ntitle = \"TOML\" # trailing\n", `{"title": "TOML"}`},
+		{"[owner]\nname = 'Tom'\n\n[database]\nports = [ 8000, 8001 ]\nenabled = true\n",
+			`{"owner": {"name": "Tom"}, "database": {"ports": [8000, 8001], "enabled": true}}`},
+		{"a.b.c = 1\na.b.d = 2\n\"quoted.key\" = 3\n'lit' = 4\n",
+			`{"a": {"b": {"c": 1, "d": 2}}, "quoted.key": 3, "lit": 4}`},
+		{"[x.y.z]\nv = 1\n[x]\nw = 2\n", `{"x": {"y": {"z": {"v": 1}}, "w": 2}}`},
+		{"[fruit]\napple.color = 'red'\n[fruit.apple.texture]\nsmooth = true\n",
+			`{"fruit": {"apple": {"color": "red", "texture": {"smooth": true}}}}`},
+		{"[[products]]\nname = 'Hammer'\n[[products]]\n[[products]]\nname = 'Nail'\n",
+			`{"products": [{"name": "Hammer"}, {}, {"name": "Nail"}]}`},
+		{"[[fruits]]\nname = 'apple'\n[fruits.physical]\ncolor = 'red'\n[[fruits.varieties]]\nname = 'red delicious'\n[[fruits]]\nname = 'banana'\n",
+			`{"fruits": [{"name": "apple", "physical": {"color": "red"}, "varieties": [{"name": "red delicious"}]}, {"name": "banana"}]}`},
+		{"point = { x = 1, y = 2 }\nempty = {}\nnested = { a.b = [1, {c = 'd'}] }\n",
+			`{"point": {"x": 1, "y": 2}, "empty": {}, "nested": {"a": {"b": [1, {"c": "d"}]}}}`},
+		{"a = [\n  1, # one\n  'two',\n  [3.0],\n]\n", `{"a": [1, "two", [3.0]]}`},
+		{"i = [+99, 42, 0, -17, 1_000, 0xDEAD_beef, 0o755, 0b1101, 9223372036854775807]\n",
+			`{"i": [99, 42, 0, -17, 1000, 3735928559, 493, 13, 9223372036854775807]}`},
+		{"f = [+1.0, 3.1415, -0.01, 5e+22, 1e06, -2E-2, 6.626e-34, 224_617.445_991]\n",
+			`{"f": [1.0, 3.1415, -0.01, 5e22, 1e6, -0.02, 6.626e-34, 224617.445991]}`},
+		{"f = [inf, +inf, -inf, nan, +nan, -nan]\nok = 1\n",
+			`{"f": ["inf", "inf", "-inf", "nan", "nan", "nan"], "ok": 1}`},
+		{`s = "tab\tquote\" \u00e9 \U0001F600 back\\slash"`, `{"s": "tab\tquote\" é 😀 back\\slash"}`},
+		{"s = \"\"\"\nRoses\nViolets\"\"\"", `{"s": "Roses\nViolets"}`},
+		{"s = \"\"\"\\\n   The quick \\\n\n   brown fox.\\\n   \"\"\"", `{"s": "The quick brown fox."}`},
+		{`s = """Here are two quotation marks: "". Simple enough."""`, `{"s": "Here are two quotation marks: \"\". Simple enough."}`},
+		{`s = """"This," she said, "is just a pointless statement.""""`, `{"s": "\"This,\" she said, \"is just a pointless statement.\""}`},
+		{`p = 'C:\Users\nodejs'`, `{"p": "C:\\Users\\nodejs"}`},
+		{"r = '''\nThe first newline is\ntrimmed in raw strings.\n'''", `{"r": "The first newline is\ntrimmed in raw strings.\n"}`},
+		{"r = ''''That,' she said.'''''", `{"r": "'That,' she said.''"}`},
+		{"odt = 1979-05-27T07:32:00Z\nodt2 = 1979-05-27 00:32:00.999999-07:00\nldt = 1979-05-27t07:32:00\nld = 1979-05-27\nlt = 00:32:00.5\n",
+			`{"odt": "1979-05-27T07:32:00Z", "odt2": "1979-05-27T00:32:00.999999-07:00", "ldt": "1979-05-27T07:32:00", "ld": "1979-05-27", "lt": "00:32:00.5"}`},
+		{"a = 1\r\nb = 2\r\n", `{"a": 1, "b": 2}`},
+	}
+	for _, tt := range tests {
+		got := Decode(tt.toml)
+		if err := got.Error(); err != nil {
+			t.Errorf("%q: %v", tt.toml, err)
+			continue
+		}
+		want := jchain.Parse(tt.json)
+		if !got.Equal(want) {
+			t.Errorf("%q: got %v, want %s", tt.toml, got, tt.json)
+		}
+	}
+
+	if k := Decode("f = 1.0").Get("f").Kind(); k != jchain.Float {
+		t.Errorf("expected 1.0 to stay a float, got %v", k)
+	}
+}
+
+func TestDecodeDatetimes(t *testing.T) {
+	doc := Decode("odt = 1979-05-27T00:32:00-07:00\nldt = 1979-05-27T07:32:00.25\nld = 1979-05-27\nlt = 07:32:00\n")
+	if got, err := doc.Get("odt").Time(); err != nil || !got.Equal(time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)) {
+		t.Errorf("odt: got %v, %v", got, err)
+	}
+	if got, err := doc.Get("ldt").Time(LocalDateTime); err != nil || got != time.Date(1979, 5, 27, 7, 32, 0, 25e7, time.UTC) {
+		t.Errorf("ldt: got %v, %v", got, err)
+	}
+	if got, err := doc.Get("ld").Time(LocalDate); err != nil || got != time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("ld: got %v, %v", got, err)
+	}
+	if got, err := doc.Get("lt").Time(LocalTime); err != nil || got.Hour() != 7 || got.Minute() != 32 {
+		t.Errorf("lt: got %v, %v", got, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"a = 1\na = 2\n":                   "duplicate key a, found 'a' at line 2, column 1",
+		"[a]\n[a]\n":                       "table a already defined, found '[' at line 2, column 1",
+		"a.b = 1\n[a]\n":                   "table a already defined, found '[' at line 2, column 1",
+		"[a.b]\n[a]\nb.c = 1\n":            "table b already defined, found 'b' at line 3, column 1",
+		"a = 1\na.b = 2\n":                 "key a is not a table, found 'a' at line 2, column 1",
+		"a = {}\n[a.b]\n":                  "cannot extend inline table a, found '[' at line 2, column 1",
+		"a = [1]\n[[a]]\n":                 "cannot append to a, which is not an array of tables, found '[' at line 2, column 1",
+		"[[a]]\n[a]\n":                     "table a already defined, found '[' at line 2, column 1",
+		"a = {b = 1}\na.c = 2\n":           "table a already defined, found 'a' at line 2, column 1",
+		"a = 1 b = 2\n":                    "expected end of line, found 'b' at line 1, column 7",
+		"a =\n":                            "expected a value, found '\\n' at line 1, column 4",
+		"= 1\n":                            "expected a key, found '=' at line 1, column 1",
+		"a = 01\n":                         "leading zeros are not allowed, found '0' at line 1, column 5",
+		"a = 1__0\n":                       "invalid number, found '1' at line 1, column 5",
+		"a = 1.\n":                         "invalid number, found '1' at line 1, column 5",
+		"a = 9223372036854775808\n":        "out of range, found '9' at line 1, column 5",
+		"a = 1e400\n":                      "1e400 cannot be represented in JSON, found '1' at line 1, column 5",
+		"a = 1979-02-30\n":                 "invalid date/time, found '1' at line 1, column 5",
+		"a = \"open\nb = 1\n":              "unterminated string, found '\\n' at line 1, column 10",
+		"a = \"\\x41\"\n":                  "invalid escape sequence, found '\\' at line 1, column 6",
+		"a = [1 2]\n":                      "expected ',' or ']', found '2' at line 1, column 8",
+		"a = [1,\n":                        "unterminated array, found '[' at line 1, column 5",
+		"a = {b = 1,}\n":                   "expected a key, found '}' at line 1, column 12",
+		"a = {b = 1\n}\n":                  "expected ',' or '}', found '\\n' at line 1, column 11",
+		"[a\n":                             "expected ']' after table name, found '\\n' at line 1, column 3",
+		"a = \"\x01\"\n":                   "control characters must be escaped, found '\\x01' at line 1, column 6",
+		"a = 1 # \x00\n":                   "control characters are not allowed in comments, found '\\x00' at line 1, column 9",
+		"a = '\xff'\n":                     "invalid UTF-8, found byte 0xff at line 1, column 6",
+		"a = " + strings.Repeat("[", 1001): "maximum depth exceeded",
+	}
+	for in, msg := range tests {
+		err := Decode(in).Error()
+		if err == nil || !strings.HasPrefix(err.Error(), msg) {
+			t.Errorf("%q: expected %q, got %v", in, msg, err)
+		}
+	}
+	if _, ok := Decode("a = ").Error().(*jchain.SyntaxError); !ok {
+		t.Error("expected a *jchain.SyntaxError")
+	}
+}
@@ -0,0 +1,1393 @@
+// Package yaml reads and writes a subset of YAML 1.2 as jchain values, so
+// that YAML configuration can be traversed with the same API as JSON.
+//
+// The reader accepts block and flow mappings and sequences; plain, quoted,
+// literal and folded scalars; anchors and aliases; the !!str, !!int,
+// !!float, !!bool, !!null, !!map and !!seq tags; and streams of several
+// documents. Plain scalars are resolved with the JSON-compatible core schema:
+// null, Null, NULL, ~ and empty values are null, true and false (also in
+// title and upper case) are booleans, decimal, 0o octal and 0x hexadecimal
+// integers are numbers, as are floats, and everything else is a string.
+// Numbers are normalized the way the JSON parser normalizes them, and mapping
+// keys are always strings. As in package toml, .inf, -.inf and .nan have no
+// JSON equivalent and become the strings "inf", "-inf" and "nan". Complex keys ("? "), merge keys and custom tags are
+// not supported.
+//
+// Errors are *jchain.SyntaxError values with the line and column of the
+// problem.
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/mntwlds/jchain"
+	"github.com/mntwlds/jchain/internal/core"
+)
+
+// defaultMaxDepth is the limit of Decode and DecodeAll, which matches
+// jchain.Parse.
+const defaultMaxDepth = 1000
+
+// defaultMaxAliasNodes is the alias expansion limit used when
+// DecodeOptions.MaxAliasNodes is zero.
+const defaultMaxAliasNodes = 100000
+
+type DecodeOptions struct {
+	// MaxDepth limits the nesting of mappings and sequences. As in
+	// jchain.ParseOptions, zero means no limit.
+	MaxDepth int
+	// MaxAliasNodes limits how many nodes aliases may add to a document,
+	// counting every node of an aliased subtree each time it is used. It
+	// guards against documents that nest aliases to grow exponentially.
+	// Zero uses a limit of 100000.
+	MaxAliasNodes int
+}
+
+// Decode reads a stream holding at most one document. An empty stream is
+// null.
+func Decode(input string) *jchain.Value {
+	return DecodeWithOptions(input, DecodeOptions{MaxDepth: defaultMaxDepth})
+}
+
+func DecodeWithOptions(input string, opts DecodeOptions) *jchain.Value {
+	docs, err := decode(input, opts, true)
+	if err != nil {
+		return core.Error(err).(*jchain.Value)
+	}
+	if len(docs) == 0 {
+		return core.Value(nil).(*jchain.Value)
+	}
+	return core.Value(docs[0]).(*jchain.Value)
+}
+
+// DecodeAll reads every document of a stream.
+func DecodeAll(input string) ([]*jchain.Value, error) {
+	return DecodeAllWithOptions(input, DecodeOptions{MaxDepth: defaultMaxDepth})
+}
+
+func DecodeAllWithOptions(input string, opts DecodeOptions) ([]*jchain.Value, error) {
+	docs, err := decode(input, opts, false)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]*jchain.Value, len(docs))
+	for i, doc := range docs {
+		values[i] = core.Value(doc).(*jchain.Value)
+	}
+	return values, nil
+}
+
+func decode(input string, opts DecodeOptions, single bool) (docs []any, err error) {
+	if opts.MaxAliasNodes <= 0 {
+		opts.MaxAliasNodes = defaultMaxAliasNodes
+	}
+	p := &parser{src: input, opts: opts}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*jchain.SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			docs, err = nil, e
+		}
+	}()
+	return p.stream(single), nil
+}
+
+type parser struct {
+	src   string
+	pos   int
+	opts  DecodeOptions
+	depth int
+	// anchors holds the anchored nodes of the current document, and aliased
+	// how many nodes its aliases have added so far.
+	anchors map[string]anchor
+	aliased int
+}
+
+type anchor struct {
+	val  any
+	size int
+}
+
+// scalarNode is a scalar before resolution. Plain scalars are resolved with
+// the core schema unless a tag says otherwise; quoted and block scalars are
+// strings.
+type scalarNode struct {
+	text  string
+	plain bool
+}
+
+func (p *parser) fail(pos int, format string, args ...any) {
+	panic(core.SyntaxError(p.src, pos, fmt.Sprintf(format, args...)))
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) at(i int) byte {
+	if i < len(p.src) {
+		return p.src[i]
+	}
+	return 0
+}
+
+func (p *parser) peek(n int) byte {
+	return p.at(p.pos + n)
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isSpaceOrEnd(c byte) bool {
+	return c == 0 || c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isFlowIndicator(c byte) bool {
+	return c == ',' || c == '[' || c == ']' || c == '{' || c == '}'
+}
+
+// column returns the byte column of pos within its line, counted from zero.
+func (p *parser) column(pos int) int {
+	return pos - (strings.LastIndexByte(p.src[:pos], '\n') + 1)
+}
+
+// marker reports whether a document marker ("---" or "...") starts at pos.
+func (p *parser) marker(pos int) bool {
+	if p.column(pos) != 0 || !isSpaceOrEnd(p.at(pos+3)) {
+		return false
+	}
+	s := p.src[pos:]
+	return strings.HasPrefix(s, "---") || strings.HasPrefix(s, "...")
+}
+
+// more reports whether the current document has content left at p.pos.
+func (p *parser) more() bool {
+	return !p.eof() && !p.marker(p.pos)
+}
+
+func (p *parser) seqItem() bool {
+	return p.peek(0) == '-' && isSpaceOrEnd(p.peek(1))
+}
+
+func (p *parser) skipInline() {
+	for !p.eof() && isBlank(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// atLineEnd reports whether only a comment or nothing is left on the line.
+func (p *parser) atLineEnd() bool {
+	switch p.peek(0) {
+	case 0, '\n', '\r':
+		return true
+	case '#':
+		return p.pos == 0 || isSpaceOrEnd(p.src[p.pos-1])
+	}
+	return false
+}
+
+func (p *parser) skipLine() {
+	for !p.eof() && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+	if !p.eof() {
+		p.pos++
+	}
+}
+
+// skipBlank moves to the next content, past blanks, comments and line
+// breaks.
+func (p *parser) skipBlank() {
+	crossed := false
+	for {
+		p.skipInline()
+		if p.eof() || !p.atLineEnd() {
+			break
+		}
+		p.skipLine()
+		crossed = true
+	}
+	if crossed && !p.eof() {
+		start := p.pos - p.column(p.pos)
+		if i := strings.IndexByte(p.src[start:p.pos], '\t'); i >= 0 {
+			p.fail(start+i, "tabs are not allowed for indentation")
+		}
+	}
+}
+
+// endLine checks that nothing but a comment follows on the line.
+func (p *parser) endLine() {
+	p.skipInline()
+	if !p.atLineEnd() {
+		p.fail(p.pos, "unexpected content after value")
+	}
+}
+
+func (p *parser) enter() {
+	p.depth++
+	if p.opts.MaxDepth > 0 && p.depth > p.opts.MaxDepth {
+		p.fail(p.pos, "maximum depth exceeded")
+	}
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) stream(single bool) []any {
+	var docs []any
+	for {
+		p.skipBlank()
+		for !p.eof() && p.src[p.pos] == '%' && p.column(p.pos) == 0 {
+			p.skipLine()
+			p.skipBlank()
+		}
+		if p.eof() {
+			return docs
+		}
+		start := p.pos
+		if p.marker(p.pos) && p.src[p.pos] == '.' {
+			p.pos += 3
+			p.endLine()
+			continue
+		}
+		if single && len(docs) == 1 {
+			p.fail(start, "expected a single document")
+		}
+		if p.marker(p.pos) {
+			p.pos += 3
+		}
+		p.anchors = map[string]anchor{}
+		p.aliased = 0
+		doc, _ := p.node(-1, true, false)
+		docs = append(docs, doc)
+		p.skipBlank()
+		if !p.more() {
+			continue
+		}
+		p.fail(p.pos, "expected end of document")
+	}
+}
+
+// node reads the node after an indicator such as "key:" or "- ", or at the
+// start of a document. Its content is on the same line or on the following
+// lines indented more than parent; with seqAtParent a sequence may also
+// start at parent's column, as YAML allows for mapping values. compact
+// allows a block mapping or sequence to start on the same line.
+func (p *parser) node(parent int, compact, seqAtParent bool) (any, int) {
+	p.skipInline()
+	if p.peek(0) == '*' {
+		val, size := p.alias()
+		p.endLine()
+		return val, size
+	}
+	start := p.pos
+	anchorName, tag := p.properties()
+	var val any = scalarNode{plain: true}
+	size := 1
+	if p.atLineEnd() {
+		p.skipBlank()
+		col := p.column(p.pos)
+		if p.more() && (col > parent || seqAtParent && col == parent && p.seqItem()) {
+			if anchorName == "" && tag == "" {
+				return p.node(parent, true, seqAtParent)
+			}
+			val, size = p.content(parent, true)
+		}
+	} else {
+		val, size = p.content(parent, compact)
+	}
+	val = p.resolve(start, tag, val)
+	if anchorName != "" {
+		p.anchors[anchorName] = anchor{val, size}
+	}
+	return val, size
+}
+
+// content reads a node starting at p.pos.
+func (p *parser) content(parent int, compact bool) (any, int) {
+	col := p.column(p.pos)
+	switch c := p.peek(0); {
+	case c == '-' && p.seqItem():
+		if !compact {
+			p.fail(p.pos, "sequence entries are not allowed here")
+		}
+		return p.sequence(col)
+	case c == '?' && isSpaceOrEnd(p.peek(1)):
+		p.fail(p.pos, "complex mapping keys are not supported")
+	case c == '|' || c == '>':
+		return p.blockText(parent), 1
+	case c == '[' || c == '{':
+		val, size := p.flow()
+		p.endLine()
+		return val, size
+	}
+	if p.isKey() {
+		if !compact {
+			p.fail(p.pos, "mapping values are not allowed here")
+		}
+		return p.mapping(col)
+	}
+	return p.scalar(parent), 1
+}
+
+func (p *parser) mapping(indent int) (any, int) {
+	p.enter()
+	obj := map[string]any{}
+	size := 1
+	for {
+		keyPos := p.pos
+		key := p.key()
+		if _, dup := obj[key]; dup {
+			p.fail(keyPos, "duplicate key %s", key)
+		}
+		val, n := p.node(indent, false, true)
+		obj[key] = val
+		size += n
+		p.skipBlank()
+		if !p.more() || p.column(p.pos) < indent {
+			break
+		}
+		if p.column(p.pos) > indent {
+			p.fail(p.pos, "bad indentation of a mapping entry")
+		}
+	}
+	p.leave()
+	return obj, size
+}
+
+func (p *parser) sequence(indent int) (any, int) {
+	p.enter()
+	var arr []any
+	size := 1
+	for {
+		p.pos++
+		val, n := p.node(indent, true, false)
+		arr = append(arr, val)
+		size += n
+		p.skipBlank()
+		if !p.more() {
+			break
+		}
+		col := p.column(p.pos)
+		if col > indent {
+			p.fail(p.pos, "bad indentation of a sequence entry")
+		}
+		if col < indent || !p.seqItem() {
+			break
+		}
+	}
+	p.leave()
+	return arr, size
+}
+
+// isKey reports whether a block mapping key followed by ':' starts at p.pos.
+func (p *parser) isKey() bool {
+	i := p.pos
+	if q := p.src[i]; q == '"' || q == '\'' {
+		for i++; i < len(p.src) && p.src[i] != '\n'; i++ {
+			if q == '"' && p.src[i] == '\\' {
+				i++
+			} else if p.src[i] == q {
+				if q == '\'' && p.at(i+1) == '\'' {
+					i++
+					continue
+				}
+				break
+			}
+		}
+		if p.at(i) != q {
+			return false
+		}
+		for i++; isBlank(p.at(i)); i++ {
+		}
+		return p.at(i) == ':' && isSpaceOrEnd(p.at(i+1))
+	}
+	for ; i < len(p.src) && p.src[i] != '\n' && p.src[i] != '\r'; i++ {
+		if p.src[i] == ':' && isSpaceOrEnd(p.at(i+1)) {
+			return true
+		}
+		if p.src[i] == '#' && i > p.pos && isBlank(p.src[i-1]) {
+			return false
+		}
+	}
+	return false
+}
+
+// key reads a block mapping key and the ':' after it. Keys are taken as
+// written, so "1:" and "true:" give the keys "1" and "true".
+func (p *parser) key() string {
+	start := p.pos
+	if !p.isKey() {
+		p.fail(start, "expected a mapping key")
+	}
+	var key string
+	switch p.peek(0) {
+	case '"':
+		key = p.doubleQuoted()
+		p.skipInline()
+	case '\'':
+		key = p.singleQuoted()
+		p.skipInline()
+	default:
+		if strings.IndexByte("[]{},:&*!|>%@`", p.peek(0)) >= 0 {
+			p.fail(start, "unsupported mapping key")
+		}
+		for !(p.src[p.pos] == ':' && isSpaceOrEnd(p.peek(1))) {
+			p.pos++
+		}
+		key = strings.TrimRight(p.src[start:p.pos], " \t")
+	}
+	p.pos++
+	return key
+}
+
+func (p *parser) properties() (anchorName, tag string) {
+	for {
+		switch p.peek(0) {
+		case '&':
+			if anchorName != "" {
+				p.fail(p.pos, "a node can only have one anchor")
+			}
+			anchorName = p.name()
+		case '!':
+			if tag != "" {
+				p.fail(p.pos, "a node can only have one tag")
+			}
+			tag = "!" + p.name()
+		default:
+			return anchorName, tag
+		}
+		p.skipInline()
+	}
+}
+
+// name reads the name after an anchor, alias or tag indicator.
+func (p *parser) name() string {
+	start := p.pos
+	p.pos++
+	for !p.eof() && !isSpaceOrEnd(p.src[p.pos]) && !isFlowIndicator(p.src[p.pos]) {
+		p.pos++
+	}
+	name := p.src[start+1 : p.pos]
+	if name == "" && p.src[start] != '!' {
+		p.fail(start, "expected a name")
+	}
+	return name
+}
+
+func (p *parser) alias() (any, int) {
+	start := p.pos
+	name := p.name()
+	a, ok := p.anchors[name]
+	if !ok {
+		p.fail(start, "unknown anchor %s", name)
+	}
+	p.aliased += a.size
+	if p.aliased > p.opts.MaxAliasNodes {
+		p.fail(start, "alias expansion limit exceeded")
+	}
+	// Every use gets its own copy, so no two places in the tree share data.
+	return clone(a.val), a.size
+}
+
+func clone(val any) any {
+	switch val := val.(type) {
+	case map[string]any:
+		obj := make(map[string]any, len(val))
+		for k, v := range val {
+			obj[k] = clone(v)
+		}
+		return obj
+	case []any:
+		arr := make([]any, len(val))
+		for i, v := range val {
+			arr[i] = clone(v)
+		}
+		return arr
+	default:
+		return val
+	}
+}
+
+// resolve turns a node into Go data according to its tag.
+func (p *parser) resolve(pos int, tag string, val any) any {
+	s, isScalar := val.(scalarNode)
+	switch tag {
+	case "":
+		if !isScalar {
+			return val
+		}
+		if !s.plain {
+			return s.text
+		}
+		v, ok := coreValue(s.text)
+		if !ok {
+			return s.text
+		}
+		if f, isFloat := v.(float64); isFloat && math.IsInf(f, 0) {
+			p.fail(pos, "%s cannot be represented in JSON", s.text)
+		}
+		return v
+	case "!", "!!str":
+		if isScalar {
+			return s.text
+		}
+	case "!!null", "!!bool", "!!int", "!!float":
+		if !isScalar {
+			break
+		}
+		v, ok := coreValue(s.text)
+		switch v := v.(type) {
+		case nil:
+			if ok && tag == "!!null" {
+				return nil
+			}
+		case bool:
+			if tag == "!!bool" {
+				return v
+			}
+		case int64, uint64:
+			if tag == "!!int" {
+				return v
+			}
+			if tag == "!!float" {
+				if n, isInt64 := v.(int64); isInt64 {
+					return float64(n)
+				}
+				return float64(v.(uint64))
+			}
+		case float64:
+			if tag == "!!float" && !math.IsInf(v, 0) {
+				return v
+			}
+		case string:
+			if tag == "!!float" {
+				return v
+			}
+		}
+	case "!!map":
+		if _, ok := val.(map[string]any); ok {
+			return val
+		}
+		if s == (scalarNode{plain: true}) {
+			return map[string]any{}
+		}
+	case "!!seq":
+		if _, ok := val.([]any); ok {
+			return val
+		}
+		if s == (scalarNode{plain: true}) {
+			return []any{}
+		}
+	default:
+		p.fail(pos, "unsupported tag %s", tag)
+	}
+	p.fail(pos, "invalid value for tag %s", tag)
+	return nil
+}
+
+// coreValue resolves a plain scalar with the core schema. ok is false when
+// the scalar is a string. Infinity and NaN come back as the strings "inf",
+// "-inf" and "nan" with ok set.
+func coreValue(text string) (v any, ok bool) {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil, true
+	case "true", "True", "TRUE":
+		return true, true
+	case "false", "False", "FALSE":
+		return false, true
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return "inf", true
+	case "-.inf", "-.Inf", "-.INF":
+		return "-inf", true
+	case ".nan", ".NaN", ".NAN":
+		return "nan", true
+	}
+	if len(text) > 2 && text[0] == '0' && (text[1] == 'o' || text[1] == 'x') {
+		base := 8
+		if text[1] == 'x' {
+			base = 16
+		}
+		if n, err := strconv.ParseUint(text[2:], base, 64); err == nil && !strings.ContainsAny(text[2:], "+-_") {
+			if n <= math.MaxInt64 {
+				return int64(n), true
+			}
+			return n, true
+		}
+		return nil, false
+	}
+	if isInt(text) {
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n, true
+		}
+		if n, err := strconv.ParseUint(strings.TrimPrefix(text, "+"), 10, 64); err == nil {
+			return n, true
+		}
+	}
+	if isFloat(text) {
+		f, _ := strconv.ParseFloat(text, 64)
+		return f, true
+	}
+	return nil, false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isInt matches [-+]?[0-9]+.
+func isInt(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isFloat matches [-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?.
+func isFloat(s string) bool {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && isDigit(s[i]); i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		exp := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		if i == exp {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// scalar reads a plain or quoted scalar in block context. Continuation lines
+// of a plain scalar must be indented more than parent.
+func (p *parser) scalar(parent int) scalarNode {
+	switch p.peek(0) {
+	case '"':
+		s := p.doubleQuoted()
+		p.endLine()
+		return scalarNode{s, false}
+	case '\'':
+		s := p.singleQuoted()
+		p.endLine()
+		return scalarNode{s, false}
+	}
+	p.checkPlainStart()
+	var sb strings.Builder
+	sb.WriteString(p.plainLine())
+	for {
+		i := p.pos
+		for isBlank(p.at(i)) {
+			i++
+		}
+		breaks := 0
+		for ; i < len(p.src) && (isBlank(p.src[i]) || p.src[i] == '\r' || p.src[i] == '\n'); i++ {
+			if p.src[i] == '\n' {
+				breaks++
+			}
+		}
+		if breaks == 0 || i >= len(p.src) || p.src[i] == '#' || p.column(i) <= parent || p.marker(i) {
+			break
+		}
+		p.pos = i
+		if breaks == 1 {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteString(strings.Repeat("\n", breaks-1))
+		}
+		sb.WriteString(p.plainLine())
+	}
+	return scalarNode{sb.String(), true}
+}
+
+func (p *parser) checkPlainStart() {
+	if strings.IndexByte("[]{},#&*!|>'\"%@`", p.peek(0)) >= 0 {
+		p.fail(p.pos, "unexpected character")
+	}
+}
+
+// plainLine reads the rest of a plain scalar's line, up to a comment.
+func (p *parser) plainLine() string {
+	start, end := p.pos, p.pos
+	for !p.eof() {
+		c := p.src[p.pos]
+		if c == '\n' || c == '\r' || c == '#' && p.pos > start && isBlank(p.src[p.pos-1]) {
+			break
+		}
+		if c == ':' && isSpaceOrEnd(p.peek(1)) {
+			p.fail(p.pos, "mapping values are not allowed here")
+		}
+		p.pos++
+		if !isBlank(c) {
+			end = p.pos
+		}
+	}
+	return p.src[start:end]
+}
+
+// blockText reads a literal (|) or folded (>) block scalar.
+func (p *parser) blockText(parent int) scalarNode {
+	literal := p.src[p.pos] == '|'
+	p.pos++
+	var chomp byte
+	indent := -1
+	for i := 0; i < 2; i++ {
+		switch c := p.peek(0); {
+		case (c == '+' || c == '-') && chomp == 0:
+			chomp = c
+			p.pos++
+		case c >= '1' && c <= '9' && indent < 0:
+			indent = parent + int(c-'0')
+			if indent < 0 {
+				indent = 0
+			}
+			p.pos++
+		}
+	}
+	p.skipInline()
+	if !p.atLineEnd() {
+		p.fail(p.pos, "invalid block scalar header")
+	}
+	p.skipLine()
+
+	type line struct {
+		text  string
+		empty bool
+	}
+	var lines []line
+	for !p.eof() && !p.marker(p.pos) {
+		end := strings.IndexByte(p.src[p.pos:], '\n')
+		if end < 0 {
+			end = len(p.src)
+		} else {
+			end += p.pos
+		}
+		raw := strings.TrimSuffix(p.src[p.pos:end], "\r")
+		spaces := len(raw) - len(strings.TrimLeft(raw, " "))
+		if indent < 0 && spaces < len(raw) {
+			if spaces <= parent {
+				break
+			}
+			indent = spaces
+		}
+		switch {
+		case spaces == len(raw) && (indent < 0 || spaces <= indent):
+			lines = append(lines, line{empty: true})
+		case spaces < indent:
+			end = -1
+		default:
+			lines = append(lines, line{text: raw[indent:]})
+		}
+		if end < 0 {
+			break
+		}
+		p.pos = end
+		if !p.eof() {
+			p.pos++
+		}
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1].empty {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	var sb strings.Builder
+	if literal {
+		for i, l := range lines {
+			if i > 0 {
+				sb.WriteByte('\n')
+			}
+			sb.WriteString(l.text)
+		}
+	} else {
+		// Lines are joined with a space, except around empty lines, which
+		// become newlines, and around more-indented lines, whose breaks are
+		// kept.
+		const (
+			none = iota
+			normal
+			more
+		)
+		prev, empties := none, 0
+		for _, l := range lines {
+			if l.empty {
+				empties++
+				continue
+			}
+			kind := normal
+			if l.text[0] == ' ' || l.text[0] == '\t' {
+				kind = more
+			}
+			switch {
+			case prev == none:
+				sb.WriteString(strings.Repeat("\n", empties))
+			case prev == normal && kind == normal && empties == 0:
+				sb.WriteByte(' ')
+			case prev == normal && kind == normal:
+				sb.WriteString(strings.Repeat("\n", empties))
+			default:
+				sb.WriteString(strings.Repeat("\n", empties+1))
+			}
+			sb.WriteString(l.text)
+			prev, empties = kind, 0
+		}
+	}
+	switch {
+	case chomp == '+':
+		if len(lines) > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(strings.Repeat("\n", trailing))
+	case chomp == 0 && len(lines) > 0:
+		sb.WriteByte('\n')
+	}
+	return scalarNode{sb.String(), false}
+}
+
+// skipTrailingBlanks skips blanks before a line break inside a quoted
+// scalar, which are not part of its value.
+func (p *parser) skipTrailingBlanks() bool {
+	i := p.pos
+	for isBlank(p.at(i)) {
+		i++
+	}
+	if c := p.at(i); c == '\n' || c == '\r' {
+		p.pos = i
+		return true
+	}
+	return false
+}
+
+// fold handles a line break inside a quoted scalar: a single break becomes
+// a space, and each further empty line a newline.
+func (p *parser) fold(sb *strings.Builder) {
+	breaks := 0
+	for ; !p.eof(); p.pos++ {
+		c := p.src[p.pos]
+		if c == '\n' {
+			breaks++
+		} else if !isBlank(c) && c != '\r' {
+			break
+		}
+	}
+	if p.marker(p.pos) {
+		p.fail(p.pos, "unterminated string")
+	}
+	if breaks <= 1 {
+		sb.WriteByte(' ')
+	} else {
+		sb.WriteString(strings.Repeat("\n", breaks-1))
+	}
+}
+
+func (p *parser) singleQuoted() string {
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			p.fail(p.pos, "unterminated string")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '\'':
+			if p.peek(1) != '\'' {
+				p.pos++
+				return sb.String()
+			}
+			sb.WriteByte('\'')
+			p.pos += 2
+		case c == '\n' || c == '\r':
+			p.fold(&sb)
+		case isBlank(c) && p.skipTrailingBlanks():
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) doubleQuoted() string {
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			p.fail(p.pos, "unterminated string")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '"':
+			p.pos++
+			return sb.String()
+		case c == '\\':
+			p.escape(&sb)
+		case c == '\n' || c == '\r':
+			p.fold(&sb)
+		case isBlank(c) && p.skipTrailingBlanks():
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+var escapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+	'/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028",
+	'P': "\u2029",
+}
+
+func (p *parser) escape(sb *strings.Builder) {
+	start := p.pos
+	c := p.peek(1)
+	p.pos += 2
+	if s, ok := escapes[c]; ok {
+		sb.WriteString(s)
+		return
+	}
+	switch c {
+	case '\r', '\n':
+		// An escaped line break joins the lines without a space.
+		if c == '\r' && p.peek(0) == '\n' {
+			p.pos++
+		}
+		p.skipInline()
+		return
+	case 'x', 'u', 'U':
+		r := p.hexRune(start, map[byte]int{'x': 2, 'u': 4, 'U': 8}[c])
+		if utf16.IsSurrogate(r) && strings.HasPrefix(p.src[p.pos:], `\u`) {
+			p.pos += 2
+			r = utf16.DecodeRune(r, p.hexRune(start, 4))
+		}
+		if !utf8.ValidRune(r) {
+			p.fail(start, "invalid escape sequence")
+		}
+		sb.WriteRune(r)
+		return
+	}
+	p.fail(start, "invalid escape sequence")
+}
+
+func (p *parser) hexRune(start, n int) rune {
+	if p.pos+n > len(p.src) {
+		p.fail(start, "invalid escape sequence")
+	}
+	v, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+	if err != nil {
+		p.fail(start, "invalid escape sequence")
+	}
+	p.pos += n
+	return rune(v)
+}
+
+// skipFlowSpace skips blanks, line breaks and comments inside the flow
+// collection opened at open.
+func (p *parser) skipFlowSpace(open int) {
+	for !p.eof() {
+		switch c := p.src[p.pos]; {
+		case isSpaceOrEnd(c):
+			p.pos++
+		case c == '#' && isSpaceOrEnd(p.src[p.pos-1]):
+			p.skipLine()
+		case p.marker(p.pos):
+			p.fail(open, "unterminated flow collection")
+		default:
+			return
+		}
+	}
+	p.fail(open, "unterminated flow collection")
+}
+
+func (p *parser) flow() (any, int) {
+	open := p.pos
+	p.enter()
+	closer := byte(']')
+	if p.src[p.pos] == '{' {
+		closer = '}'
+	}
+	p.pos++
+	arr, obj := []any{}, map[string]any{}
+	size := 1
+	for {
+		p.skipFlowSpace(open)
+		if p.peek(0) == closer {
+			p.pos++
+			break
+		}
+		var val any
+		n := 1
+		if closer == '}' {
+			keyPos := p.pos
+			key := p.flowKey(open)
+			if _, dup := obj[key]; dup {
+				p.fail(keyPos, "duplicate key %s", key)
+			}
+			p.skipFlowSpace(open)
+			if p.peek(0) == ':' {
+				p.pos++
+				val, n = p.flowNode(open)
+			}
+			obj[key] = val
+		} else {
+			if p.peek(0) == ',' {
+				p.fail(p.pos, "unexpected ','")
+			}
+			val, n = p.flowNode(open)
+			p.skipFlowSpace(open)
+			if p.peek(0) == ':' {
+				p.fail(p.pos, "mappings inside flow sequences are not supported")
+			}
+			arr = append(arr, val)
+		}
+		size += n
+		p.skipFlowSpace(open)
+		if c := p.peek(0); c == closer {
+			p.pos++
+			break
+		} else if c != ',' {
+			p.fail(p.pos, "expected ',' or '%c'", closer)
+		}
+		p.pos++
+	}
+	p.leave()
+	if closer == '}' {
+		return obj, size
+	}
+	return arr, size
+}
+
+func (p *parser) flowKey(open int) string {
+	switch c := p.peek(0); {
+	case c == '"':
+		return p.doubleQuoted()
+	case c == '\'':
+		return p.singleQuoted()
+	case c == ':' || isFlowIndicator(c):
+		p.fail(p.pos, "expected a mapping key")
+	}
+	return p.flowPlain(open)
+}
+
+func (p *parser) flowNode(open int) (any, int) {
+	p.skipFlowSpace(open)
+	if p.peek(0) == '*' {
+		return p.alias()
+	}
+	start := p.pos
+	anchorName, tag := p.properties()
+	p.skipFlowSpace(open)
+	var val any
+	size := 1
+	switch c := p.peek(0); c {
+	case '[', '{':
+		val, size = p.flow()
+	case '"':
+		val = scalarNode{p.doubleQuoted(), false}
+	case '\'':
+		val = scalarNode{p.singleQuoted(), false}
+	case ',', ']', '}':
+		val = scalarNode{plain: true}
+	default:
+		val = scalarNode{p.flowPlain(open), true}
+	}
+	val = p.resolve(start, tag, val)
+	if anchorName != "" {
+		p.anchors[anchorName] = anchor{val, size}
+	}
+	return val, size
+}
+
+// flowPlain reads a plain scalar inside a flow collection, where it ends at
+// a flow indicator or a ':' followed by a space or an indicator.
+func (p *parser) flowPlain(open int) string {
+	p.checkPlainStart()
+	var sb strings.Builder
+	for {
+		start, end := p.pos, p.pos
+		for !p.eof() {
+			c := p.src[p.pos]
+			if c == '\n' || c == '\r' || isFlowIndicator(c) ||
+				c == ':' && (isSpaceOrEnd(p.peek(1)) || isFlowIndicator(p.peek(1))) ||
+				c == '#' && p.pos > start && isBlank(p.src[p.pos-1]) {
+				break
+			}
+			p.pos++
+			if !isBlank(c) {
+				end = p.pos
+			}
+		}
+		sb.WriteString(p.src[start:end])
+
+		i, breaks := p.pos, 0
+		for ; isSpaceOrEnd(p.at(i)) && i < len(p.src); i++ {
+			if p.src[i] == '\n' {
+				breaks++
+			}
+		}
+		if breaks == 0 || i >= len(p.src) || strings.IndexByte(",[]{}#:", p.src[i]) >= 0 || p.marker(i) {
+			return sb.String()
+		}
+		p.pos = i
+		if breaks == 1 {
+			sb.WriteByte(' ')
+		} else {
+			sb.WriteString(strings.Repeat("\n", breaks-1))
+		}
+	}
+}
+
+// Encode writes v as a block-style YAML document, with mapping keys sorted
+// and strings quoted wherever they would otherwise read back as another
+// type. Multi-line strings are written as literal block scalars.
+func Encode(v *jchain.Value) ([]byte, error) {
+	data, err := v.Any()
+	if err != nil {
+		return nil, err
+	}
+	return appendDocument(nil, data), nil
+}
+
+// EncodeAll writes a stream with one document per value, each starting with
+// a "---" marker.
+func EncodeAll(values ...*jchain.Value) ([]byte, error) {
+	var buf []byte
+	for _, v := range values {
+		data, err := v.Any()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, "---\n"...)
+		buf = appendDocument(buf, data)
+	}
+	return buf, nil
+}
+
+func appendDocument(buf []byte, data any) []byte {
+	switch data := data.(type) {
+	case map[string]any:
+		if len(data) > 0 {
+			return appendMapping(buf, data, 0, false)
+		}
+	case []any:
+		if len(data) > 0 {
+			return appendSequence(buf, data, 0, false)
+		}
+	}
+	return appendScalar(buf, data, 2)
+}
+
+// appendMapping writes m with its keys at column indent. With inline the
+// first key continues the current line, after a "- ".
+func appendMapping(buf []byte, m map[string]any, indent int, inline bool) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i > 0 || !inline {
+			buf = append(buf, strings.Repeat(" ", indent)...)
+		}
+		buf = appendString(buf, k, -1)
+		buf = append(buf, ':')
+		buf = appendChild(buf, m[k], indent, true)
+	}
+	return buf
+}
+
+func appendSequence(buf []byte, arr []any, indent int, inline bool) []byte {
+	for i, item := range arr {
+		if i > 0 || !inline {
+			buf = append(buf, strings.Repeat(" ", indent)...)
+		}
+		buf = append(buf, '-')
+		buf = appendChild(buf, item, indent, false)
+	}
+	return buf
+}
+
+// appendChild writes the value after a "key:" or "-" at column indent.
+// Nested mappings and sequences go on the following lines after a key, and
+// start on the same line after a "-".
+func appendChild(buf []byte, v any, indent int, afterKey bool) []byte {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) > 0 {
+			if afterKey {
+				return appendMapping(append(buf, '\n'), v, indent+2, false)
+			}
+			return appendMapping(append(buf, ' '), v, indent+2, true)
+		}
+	case []any:
+		if len(v) > 0 {
+			if afterKey {
+				return appendSequence(append(buf, '\n'), v, indent+2, false)
+			}
+			return appendSequence(append(buf, ' '), v, indent+2, true)
+		}
+	}
+	return appendScalar(append(buf, ' '), v, indent+2)
+}
+
+// appendScalar writes a scalar and the line break after it. Block scalars
+// indent their lines to indent.
+func appendScalar(buf []byte, v any, indent int) []byte {
+	switch v := v.(type) {
+	case nil:
+		buf = append(buf, "null"...)
+	case bool:
+		buf = strconv.AppendBool(buf, v)
+	case int64:
+		buf = strconv.AppendInt(buf, v, 10)
+	case uint64:
+		buf = strconv.AppendUint(buf, v, 10)
+	case *big.Int:
+		buf = v.Append(buf, 10)
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		buf = append(buf, s...)
+		if !strings.ContainsAny(s, ".e") {
+			buf = append(buf, ".0"...)
+		}
+	case string:
+		buf = appendString(buf, v, indent)
+	case map[string]any:
+		buf = append(buf, "{}"...)
+	case []any:
+		buf = append(buf, "[]"...)
+	}
+	return append(buf, '\n')
+}
+
+// appendString writes s plain when that reads back as the same string, as a
+// literal block scalar indented to indent when it spans lines, and double
+// quoted otherwise. A negative indent rules out block scalars, for keys.
+func appendString(buf []byte, s string, indent int) []byte {
+	if isPlainSafe(s) {
+		return append(buf, s...)
+	}
+	if indent >= 0 && isLiteralSafe(s) {
+		body := strings.TrimRight(s, "\n")
+		trailing := len(s) - len(body)
+		switch trailing {
+		case 0:
+			buf = append(buf, "|-"...)
+		case 1:
+			buf = append(buf, '|')
+		default:
+			buf = append(buf, "|+"...)
+		}
+		for _, line := range strings.Split(body, "\n") {
+			buf = append(buf, '\n')
+			if line != "" {
+				buf = append(buf, strings.Repeat(" ", indent)...)
+				buf = append(buf, line...)
+			}
+		}
+		for i := 1; i < trailing; i++ {
+			buf = append(buf, '\n')
+		}
+		return buf
+	}
+	return appendQuoted(buf, s)
+}
+
+func isPrintable(r rune) bool {
+	return r >= 0x20 && r != 0x7f && unicode.IsPrint(r)
+}
+
+func isPlainSafe(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || strings.IndexByte("-?:,[]{}#&*!|>'\"%@`", s[0]) >= 0 {
+		return false
+	}
+	if strings.HasPrefix(s, "...") || strings.HasSuffix(s, ":") || strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return false
+	}
+	for _, r := range s {
+		if !isPrintable(r) {
+			return false
+		}
+	}
+	_, ok := coreValue(s)
+	return !ok
+}
+
+// isLiteralSafe reports whether s reads back unchanged from a literal block
+// scalar: it must span lines and hold only printable characters and tabs,
+// its first line cannot start with a space, which would be taken as
+// indentation, and lines holding only blanks are not kept exactly.
+func isLiteralSafe(s string) bool {
+	body := strings.TrimRight(s, "\n")
+	if body == "" || !strings.Contains(s, "\n") || body[0] == ' ' {
+		return false
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if line != "" && strings.Trim(line, " \t") == "" {
+			return false
+		}
+		for _, r := range line {
+			if r != '\t' && !isPrintable(r) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func appendQuoted(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			buf = append(buf, '\\', byte(r))
+		case r == '\n':
+			buf = append(buf, `\n`...)
+		case r == '\t':
+			buf = append(buf, `\t`...)
+		case r == '\r':
+			buf = append(buf, `\r`...)
+		case isPrintable(r):
+			buf = utf8.AppendRune(buf, r)
+		case r <= 0xffff:
+			buf = append(buf, fmt.Sprintf(`\u%04x`, r)...)
+		default:
+			buf = append(buf, fmt.Sprintf(`\U%08x`, r)...)
+		}
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,236 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mntwlds/jchain"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		yaml, json string
+	}{
+		{"", `null`},
+		{"# only a comment\n", `null`},
+		{"a: 1\nb: two\n", `{"a": 1, "b": "two"}`},
+		{"server:\n  host: localhost\n  port: 8080\n", `{"server": {"host": "localhost", "port": 8080}}`},
+		{"- a\n- b\n-\n", `["a", "b", null]`},
+		{"list:\n- 1\n- 2\nnext: x\n", `{"list": [1, 2], "next": "x"}`},
+		{"list:\n  - 1\n  - 2\n", `{"list": [1, 2]}`},
+		{"- name: a\n  tags: [x, y]\n- name: b\n", `[{"name": "a", "tags": ["x", "y"]}, {"name": "b"}]`},
+		{"- - 1\n  - 2\n- - 3\n", `[[1, 2], [3]]`},
+		{"{a: 1, b: [true, null], 'c d': \"e\"}", `{"a": 1, "b": [true, null], "c d": "e"}`},
+		{"[a b, {x: y},\n  c,]", `["a b", {"x": "y"}, "c"]`},
+		{`{"a":1, b}`, `{"a": 1, "b": null}`},
+		{"[1, 2] # trailing comment", `[1, 2]`},
+		{"a:\nb: ~\nc: Null\n", `{"a": null, "b": null, "c": null}`},
+		{"[true, True, TRUE, false, yes, on]", `[true, true, true, false, "yes", "on"]`},
+		{"[0, -12, +7, 007, 0o17, 0x1F, 0xffffffffffffffff]", `[0, -12, 7, 7, 15, 31, 18446744073709551615]`},
+		{"[1.5, -.5, 1e3, 2., 6.02E+23]", `[1.5, -0.5, 1000.0, 2.0, 6.02e23]`},
+		{"[.inf, +.Inf, -.INF, .nan, !!float .NaN, '.inf']", `["inf", "inf", "-inf", "nan", "nan", ".inf"]`},
+		{"[1_000, 0b101, 1.2.3, 12:30, -, .]", `["1_000", "0b101", "1.2.3", "12:30", "-", "."]`},
+		{"url: http://example.com:80/x\n", `{"url": "http://example.com:80/x"}`},
+		{"a: b#c # comment\n", `{"a": "b#c"}`},
+		{"1: one\ntrue: yes\n", `{"1": "one", "true": "yes"}`},
+		{"text: a long\n  plain scalar\n\n  folded\n", `{"text": "a long plain scalar\nfolded"}`},
+		{`'it''s' `, `"it's"`},
+		{"'one\n  two\n\n  three'", `"one two\nthree"`},
+		{`"tab\there \"q\" \u00e9 \U0001F600 \x41 \/ \\"`, `"tab\there \"q\" é 😀 A / \\"`},
+		{`"\ud83d\ude00"`, `"😀"`},
+		{"\"line \\\n    joined\"", `"line joined"`},
+		{"a: !!str 123\nb: !!float 1\nc: !!int \"42\"\nd: !!str\n", `{"a": "123", "b": 1.0, "c": 42, "d": ""}`},
+		{"a: !!map\nb: !!seq\n", `{"a": {}, "b": []}`},
+		{"%YAML 1.2\n---\na: 1\n...\n", `{"a": 1}`},
+		{"--- text\n", `"text"`},
+		{"key: 'quoted': x", ``},
+	}
+	for _, tt := range tests {
+		got := Decode(tt.yaml)
+		if tt.json == "" {
+			if got.Error() == nil {
+				t.Errorf("%q: expected an error, got %v", tt.yaml, got)
+			}
+			continue
+		}
+		if err := got.Error(); err != nil {
+			t.Errorf("%q: %v", tt.yaml, err)
+			continue
+		}
+		want := jchain.Parse(tt.json)
+		if !got.Equal(want) || got.Kind() != want.Kind() {
+			t.Errorf("%q: got %v, want %s", tt.yaml, got, tt.json)
+		}
+	}
+}
+
+func TestDecodeBlockScalars(t *testing.T) {
+	tests := []struct {
+		yaml, want string
+	}{
+		{"a: |\n  one\n  two\n\nb: x\n", "one\ntwo\n"},
+		{"a: |-\n  one\n  two\n\n", "one\ntwo"},
+		{"a: |+\n  one\n\n\nb: x\n", "one\n\n\n"},
+		{"a: |\n  one\n    indented\n  # not a comment\n", "one\n  indented\n# not a comment\n"},
+		{"a: |2\n    lead\n  x\n", "  lead\nx\n"},
+		{"a: >\n  folded\n  text\n\n  para\n", "folded text\npara\n"},
+		{"a: >-\n  one\n    more\n  two\n", "one\n  more\ntwo"},
+		{"a: |\nb: x\n", ""},
+		{"- |\n  item\n- x\n", "item\n"},
+	}
+	for _, tt := range tests {
+		got := Decode(tt.yaml)
+		v := got.Get("a")
+		if got.Kind() == jchain.Array {
+			v = got.Index(0)
+		}
+		if s, err := v.String(); err != nil || s != tt.want {
+			t.Errorf("%q: got %q, %v, want %q", tt.yaml, s, err, tt.want)
+		}
+	}
+}
+
+func TestDecodeAnchors(t *testing.T) {
+	got := Decode("base: &b\n  x: 1\n  y: [2]\ncopy: *b\nlist: [&n 5, *n]\n")
+	want := jchain.Parse(`{"base": {"x": 1, "y": [2]}, "copy": {"x": 1, "y": [2]}, "list": [5, 5]}`)
+	if !got.Equal(want) {
+		t.Errorf("got %v, %v", got, got.Error())
+	}
+	if copied, err := got.Get("copy").Map(); err == nil {
+		copied["x"] = int64(2)
+	}
+	if x, _ := got.Get("base").Get("x").Int(); x != 1 {
+		t.Error("expected an alias to get its own copy of the anchored node")
+	}
+
+	laughs := "a: &a [x, x, x, x, x, x, x, x, x, x]\n"
+	for _, name := range []string{"b", "c", "d", "e", "f", "g"} {
+		prev := string(rune(name[0] - 1))
+		laughs += name + ": &" + name + " [" + strings.TrimSuffix(strings.Repeat("*"+prev+", ", 10), ", ") + "]\n"
+	}
+	if err := Decode(laughs).Error(); err == nil || !strings.HasPrefix(err.Error(), "alias expansion limit exceeded") {
+		t.Errorf("expected expansion limit, got %v", err)
+	}
+	if err := DecodeWithOptions("a: &a [1, 2]\nb: [*a, *a]\n", DecodeOptions{MaxAliasNodes: 5}).Error(); err == nil {
+		t.Error("expected the configured limit to apply")
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	docs, err := DecodeAll("# stream\na: 1\n---\n- x\n--- 3\n...\n---\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`{"a": 1}`, `["x"]`, `3`, `null`}
+	if len(docs) != len(want) {
+		t.Fatalf("got %d documents, want %d", len(docs), len(want))
+	}
+	for i, doc := range docs {
+		if !doc.Equal(jchain.Parse(want[i])) {
+			t.Errorf("document %d: got %v, want %s", i, doc, want[i])
+		}
+	}
+
+	if docs, err := DecodeAll("--- &a x\n--- *a\n"); err == nil {
+		t.Errorf("expected anchors to be scoped to their document, got %v", docs)
+	}
+	if err := Decode("a: 1\n---\nb: 2\n").Error(); err == nil || !strings.HasPrefix(err.Error(), "expected a single document, found '-' at line 2, column 1") {
+		t.Errorf("expected single document error, got %v", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := map[string]string{
+		"a:\n  b: 1\n c: 2\n":  "bad indentation of a mapping entry, found 'c' at line 3, column 2",
+		"a: 1\na: 2\n":         "duplicate key a, found 'a' at line 2, column 1",
+		"- a\n  - b\nc: d\n":   "expected end of document, found 'c' at line 3, column 1",
+		"a: b: c\n":            "mapping values are not allowed here, found 'b' at line 1, column 4",
+		"a:\n\tb: 1\n":         "tabs are not allowed for indentation, found '\\t' at line 2, column 1",
+		"[1, 2\n":              "unterminated flow collection, found '[' at line 1, column 1",
+		"{a: 1 b: 2}":          "expected ',' or '}', found ':' at line 1, column 8",
+		"\"abc":                "unterminated string, found end of input at line 1, column 5",
+		`"\q"`:                 "invalid escape sequence, found '\\' at line 1, column 2",
+		"a: *nope\n":           "unknown anchor nope, found '*' at line 1, column 4",
+		"a: !!int x\n":         "invalid value for tag !!int, found '!' at line 1, column 4",
+		"a: !custom x\n":       "unsupported tag !custom, found '!' at line 1, column 4",
+		"? a\n: b\n":           "complex mapping keys are not supported, found '?' at line 1, column 1",
+		"a: 1e999\n":           "1e999 cannot be represented in JSON, found '1' at line 1, column 4",
+		"a: [1]]\n":            "unexpected content after value, found ']' at line 1, column 7",
+		"a:\n  b:\n    c: 1\n": "maximum depth exceeded, found 'c' at line 3, column 5",
+	}
+	for in, msg := range tests {
+		err := DecodeWithOptions(in, DecodeOptions{MaxDepth: 2}).Error()
+		if err == nil || !strings.HasPrefix(err.Error(), msg) {
+			t.Errorf("%q: expected %q, got %v", in, msg, err)
+		}
+	}
+	if _, ok := Decode("a: [").Error().(*jchain.SyntaxError); !ok {
+		t.Error("expected a *jchain.SyntaxError")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		json, yaml string
+	}{
+		{`null`, "null\n"},
+		{`"text"`, "text\n"},
+		{`1.0`, "1.0\n"},
+		{`1e21`, "1e+21\n"},
+		{`{}`, "{}\n"},
+		{`{"b": [1, {"x": true, "y": []}], "a": {"c": null}}`,
+			"a:\n  c: null\nb:\n  - 1\n  - x: true\n    y: []\n"},
+		{`[[1, 2], "a"]`, "- - 1\n  - 2\n- a\n"},
+		{`["true", "1", "", " pad", "a: b", "#x", "null", "1e3", "0x1F", "...", "é"]`,
+			"- \"true\"\n- \"1\"\n- \"\"\n- \" pad\"\n- \"a: b\"\n- \"#x\"\n- \"null\"\n- \"1e3\"\n- \"0x1F\"\n- \"...\"\n- é\n"},
+		{`{"a b": 1, "c:": 2, "-": 3}`, "\"-\": 3\na b: 1\n\"c:\": 2\n"},
+		{`{"s": "one\ntwo\n", "t": "x\n\ny", "u": "z\n\n"}`,
+			"s: |\n  one\n  two\nt: |-\n  x\n\n  y\nu: |+\n  z\n\n"},
+		{`[" lead\nx", "tab\t\u0001"]`, "- \" lead\\nx\"\n- \"tab\\t\\u0001\"\n"},
+	}
+	for _, tt := range tests {
+		v := jchain.Parse(tt.json)
+		got, err := Encode(v)
+		if err != nil {
+			t.Errorf("%s: %v", tt.json, err)
+			continue
+		}
+		if string(got) != tt.yaml {
+			t.Errorf("%s: got %q, want %q", tt.json, got, tt.yaml)
+		}
+		if back := Decode(string(got)); !back.Equal(v) || back.Kind() != v.Kind() {
+			t.Errorf("%s: round trip gave %v, %v", tt.json, back, back.Error())
+		}
+	}
+
+	stream, err := EncodeAll(jchain.Parse(`{"a": 1}`), jchain.Parse(`[2]`))
+	if err != nil || string(stream) != "---\na: 1\n---\n- 2\n" {
+		t.Errorf("got %q, %v", stream, err)
+	}
+	if _, err := Encode(jchain.Parse(`{`)); err == nil {
+		t.Error("expected the chain error to be returned")
+	}
+}
+
+func TestDecodeDepth(t *testing.T) {
+	nested := func(n int) string {
+		return strings.Repeat("[", n) + strings.Repeat("]", n)
+	}
+	v := jchain.Parse(nested(1000))
+	data, err := Encode(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back := Decode(string(data)); !back.Equal(v) {
+		t.Errorf("round trip of 1000 levels gave %v", back.Error())
+	}
+	deeper := nested(1001)
+	if err := Decode(deeper).Error(); err == nil || !strings.HasPrefix(err.Error(), "maximum depth exceeded") {
+		t.Errorf("expected Decode to stop at 1000 levels, got %v", err)
+	}
+	for _, max := range []int{0, 5000} {
+		if err := DecodeWithOptions(deeper, DecodeOptions{MaxDepth: max}).Error(); err != nil {
+			t.Errorf("MaxDepth %d: %v", max, err)
+		}
+	}
+}